@@ -0,0 +1,232 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"bytes"
+	"html"
+	"net/http"
+	"strings"
+)
+
+// maxAdvertiseBuffer is the maximum number of bytes of an HTML response that
+// are buffered while looking for the <head> element.  If the <head> start tag
+// is not found within this many bytes, the response is passed through
+// unchanged.
+const maxAdvertiseBuffer = 64 << 10
+
+// An AdvertiseOption configures the handler returned by Advertise.
+type AdvertiseOption func(*advertiser)
+
+// AdvertisePaths limits advertising the endpoint to requests whose URL path
+// begins with one of the provided prefixes.  If not specified, the endpoint
+// is advertised for all requests.
+func AdvertisePaths(prefixes ...string) AdvertiseOption {
+	return func(a *advertiser) {
+		a.paths = append(a.paths, prefixes...)
+	}
+}
+
+// AdvertiseHTML enables inserting a <link rel="webmention"> element into the
+// <head> of HTML responses, in addition to the HTTP Link header.  Responses
+// are rewritten as they are written, buffering only until the <head> start
+// tag is found.  Only complete, uncompressed 200 OK responses are rewritten,
+// so partial content is never altered.  Rewritten responses have a weak ETag
+// and do not accept range requests, since their body differs from the one
+// the wrapped handler served.
+func AdvertiseHTML(enabled bool) AdvertiseOption {
+	return func(a *advertiser) {
+		a.html = enabled
+	}
+}
+
+// Advertise returns an http.Handler that advertises endpoint as the
+// webmention endpoint for responses served by next, by adding an HTTP Link
+// header with a webmention rel value.  Responses that already include a
+// webmention Link header are left unchanged.
+func Advertise(endpoint string, next http.Handler, opts ...AdvertiseOption) http.Handler {
+	a := &advertiser{endpoint: endpoint, next: next}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+type advertiser struct {
+	endpoint string
+	next     http.Handler
+	paths    []string
+	html     bool
+}
+
+func (a *advertiser) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !a.match(r.URL.Path) {
+		a.next.ServeHTTP(w, r)
+		return
+	}
+	aw := &advertiseWriter{ResponseWriter: w, a: a}
+	defer aw.finish()
+	a.next.ServeHTTP(aw, r)
+}
+
+// match reports whether the endpoint should be advertised for path.
+func (a *advertiser) match(path string) bool {
+	if len(a.paths) == 0 {
+		return true
+	}
+	for _, p := range a.paths {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// advertiseWriter is an http.ResponseWriter that adds the webmention Link
+// header, and optionally inserts a <link> element into HTML responses.
+type advertiseWriter struct {
+	http.ResponseWriter
+	a *advertiser
+
+	wroteHeader bool
+	rewrite     bool   // whether the body is being rewritten
+	buf         []byte // body buffered while looking for <head>
+}
+
+func (w *advertiseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		// informational responses may be followed by the final response
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	if len(httpLinks(h)) == 0 {
+		h.Add("Link", "<"+w.a.endpoint+`>; rel="webmention"`)
+		w.rewrite = w.a.html && code == http.StatusOK && isHTML(h) &&
+			h.Get("Content-Encoding") == "" && h.Get("Content-Range") == ""
+		if w.rewrite {
+			// the rewritten body differs from the one these describe
+			h.Del("Content-Length")
+			h.Set("Accept-Ranges", "none")
+			if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				h.Set("ETag", "W/"+etag)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *advertiseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.rewrite {
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	i, ok := headEnd(w.buf)
+	switch {
+	case ok:
+		var out bytes.Buffer
+		out.Write(w.buf[:i])
+		out.WriteString(`<link rel="webmention" href="` + html.EscapeString(w.a.endpoint) + `">`)
+		out.Write(w.buf[i:])
+		w.rewrite, w.buf = false, nil
+		if _, err := w.ResponseWriter.Write(out.Bytes()); err != nil {
+			return 0, err
+		}
+	case i < 0 || len(w.buf) > maxAdvertiseBuffer:
+		// no <head> element; pass through unchanged
+		if err := w.flushBuffer(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes any buffered data, giving up on inserting a <link> element if
+// the <head> element has not yet been found, and flushes the underlying
+// ResponseWriter if it supports it.
+func (w *advertiseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = w.flushBuffer()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter, for use by
+// http.ResponseController.
+func (w *advertiseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// flushBuffer writes any buffered data unchanged and stops rewriting.
+func (w *advertiseWriter) flushBuffer() error {
+	buf := w.buf
+	w.rewrite, w.buf = false, nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// finish completes the response after the wrapped handler returns.
+func (w *advertiseWriter) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = w.flushBuffer()
+}
+
+// isHTML reports whether h describes an HTML response.
+func isHTML(h http.Header) bool {
+	ct := strings.ToLower(h.Get("Content-Type"))
+	return strings.HasPrefix(ct, "text/html") || strings.HasPrefix(ct, "application/xhtml+xml")
+}
+
+// headEnd returns the offset in b just after the <head> start tag.  If the
+// start tag is not complete, ok is false and i is zero if more data may yet
+// contain it, or negative if b has reached the <body> without a <head>.
+func headEnd(b []byte) (i int, ok bool) {
+	lower := bytes.ToLower(b)
+	for off := 0; ; {
+		j := bytes.Index(lower[off:], []byte("<head"))
+		if j < 0 {
+			if bytes.Contains(lower, []byte("<body")) {
+				return -1, false
+			}
+			return 0, false
+		}
+		j += off + len("<head")
+		if j == len(lower) {
+			return 0, false // tag name may continue
+		}
+		if c := lower[j]; c != '>' && c != '/' && !isSpace(c) {
+			off = j // another element, such as <header>
+			continue
+		}
+		k := bytes.IndexByte(lower[j:], '>')
+		if k < 0 {
+			return 0, false
+		}
+		return j + k + 1, true
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,253 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAdvertise(t *testing.T) {
+	page := "<html><head><title>t</title></head><body><header>h</header></body></html>"
+	tests := []struct {
+		description string
+		path        string
+		opts        []AdvertiseOption
+		handler     http.HandlerFunc
+		wantLink    []string
+		wantBody    string
+	}{
+		{
+			description: "header only",
+			path:        "/",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(page))
+			},
+			wantLink: []string{`</endpoint>; rel="webmention"`},
+			wantBody: page,
+		},
+		{
+			description: "path not matched",
+			path:        "/static/a.css",
+			opts:        []AdvertiseOption{AdvertisePaths("/blog/")},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(page))
+			},
+			wantBody: page,
+		},
+		{
+			description: "path matched",
+			path:        "/blog/post",
+			opts:        []AdvertiseOption{AdvertisePaths("/about", "/blog/")},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(page))
+			},
+			wantLink: []string{`</endpoint>; rel="webmention"`},
+			wantBody: page,
+		},
+		{
+			description: "existing link header",
+			path:        "/",
+			opts:        []AdvertiseOption{AdvertiseHTML(true)},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Link", "</other>; rel=webmention")
+				_, _ = w.Write([]byte(page))
+			},
+			wantLink: []string{"</other>; rel=webmention"},
+			wantBody: page,
+		},
+		{
+			description: "html rewrite",
+			path:        "/",
+			opts:        []AdvertiseOption{AdvertiseHTML(true)},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(page))
+			},
+			wantLink: []string{`</endpoint>; rel="webmention"`},
+			wantBody: `<html><head><link rel="webmention" href="/endpoint"><title>t</title></head><body><header>h</header></body></html>`,
+		},
+		{
+			description: "html rewrite across writes",
+			path:        "/",
+			opts:        []AdvertiseOption{AdvertiseHTML(true)},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Header().Set("Content-Length", "100")
+				for _, s := range []string{"<html><HE", `AD lang="en"`, "><title>t</title></head>", "<body></body></html>"} {
+					_, _ = w.Write([]byte(s))
+				}
+			},
+			wantLink: []string{`</endpoint>; rel="webmention"`},
+			wantBody: `<html><HEAD lang="en"><link rel="webmention" href="/endpoint"><title>t</title></head><body></body></html>`,
+		},
+		{
+			description: "html without head",
+			path:        "/",
+			opts:        []AdvertiseOption{AdvertiseHTML(true)},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte("<body><header>h</header>"))
+				_, _ = w.Write([]byte("</body>"))
+			},
+			wantLink: []string{`</endpoint>; rel="webmention"`},
+			wantBody: "<body><header>h</header></body>",
+		},
+		{
+			description: "not html",
+			path:        "/",
+			opts:        []AdvertiseOption{AdvertiseHTML(true)},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte("<head>"))
+			},
+			wantLink: []string{`</endpoint>; rel="webmention"`},
+			wantBody: "<head>",
+		},
+		{
+			description: "compressed",
+			path:        "/",
+			opts:        []AdvertiseOption{AdvertiseHTML(true)},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write([]byte("<head>"))
+			},
+			wantLink: []string{`</endpoint>; rel="webmention"`},
+			wantBody: "<head>",
+		},
+		{
+			description: "not ok",
+			path:        "/",
+			opts:        []AdvertiseOption{AdvertiseHTML(true)},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte("<head>"))
+			},
+			wantLink: []string{`</endpoint>; rel="webmention"`},
+			wantBody: "<head>",
+		},
+		{
+			description: "no body",
+			path:        "/",
+			opts:        []AdvertiseOption{AdvertiseHTML(true)},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantLink: []string{`</endpoint>; rel="webmention"`},
+		},
+	}
+
+	for _, tt := range tests {
+		h := Advertise("/endpoint", tt.handler, tt.opts...)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if got := rec.Result().Header.Values("Link"); !cmp.Equal(got, tt.wantLink) {
+			t.Errorf("%v: Link header = %q, want %q", tt.description, got, tt.wantLink)
+		}
+		if got := rec.Body.String(); got != tt.wantBody {
+			t.Errorf("%v: body = %q, want %q", tt.description, got, tt.wantBody)
+		}
+		if tt.wantBody != page && rec.Result().Header.Get("Content-Length") != "" {
+			t.Errorf("%v: Content-Length header not removed from rewritten response", tt.description)
+		}
+	}
+}
+
+func TestAdvertise_Discovery(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	mux.Handle("/", Advertise("/endpoint", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><head></head><body></body></html>"))
+	}), AdvertiseHTML(true)))
+
+	endpoint := server.URL + "/endpoint"
+	tests := []struct {
+		strategy       DiscoveryStrategy
+		wantCandidates []EndpointCandidate
+	}{
+		{HeadOnly, nil},
+		{GetOnly, []EndpointCandidate{{Endpoint: endpoint, Source: SourceLink}}},
+	}
+	for _, tt := range tests {
+		client := NewWithOptions(WithDiscoveryStrategy(tt.strategy))
+		d, err := client.DiscoverEndpointDetailed(server.URL + "/")
+		if err != nil {
+			t.Fatalf("strategy %v: DiscoverEndpointDetailed returned error: %v", tt.strategy, err)
+		}
+		if d.Endpoint != endpoint || d.Source != SourceHeader {
+			t.Errorf("strategy %v: DiscoverEndpointDetailed returned endpoint %q from %v, want %q from %v", tt.strategy, d.Endpoint, d.Source, endpoint, SourceHeader)
+		}
+		if !cmp.Equal(d.Candidates, tt.wantCandidates) {
+			t.Errorf("strategy %v: DiscoverEndpointDetailed returned candidates %v, want %v", tt.strategy, d.Candidates, tt.wantCandidates)
+		}
+	}
+}
+
+func TestAdvertise_ServeContent(t *testing.T) {
+	content := "<html><head><title>t</title></head><body>hello</body></html>"
+	h := Advertise("/endpoint", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "page.html", time.Time{}, strings.NewReader(content))
+	}), AdvertiseHTML(true))
+
+	tests := []struct {
+		description string
+		rangeHeader string
+		wantStatus  int
+		wantBody    string
+		wantHeader  http.Header
+	}{
+		{
+			description: "full response",
+			wantStatus:  http.StatusOK,
+			wantBody:    `<html><head><link rel="webmention" href="/endpoint"><title>t</title></head><body>hello</body></html>`,
+			wantHeader: http.Header{
+				"Etag":           {`W/"v1"`},
+				"Accept-Ranges":  {"none"},
+				"Content-Length": nil,
+			},
+		},
+		{
+			description: "range request",
+			rangeHeader: "bytes=0-19",
+			wantStatus:  http.StatusPartialContent,
+			wantBody:    content[:20],
+			wantHeader: http.Header{
+				"Etag":           {`"v1"`},
+				"Content-Range":  {"bytes 0-19/60"},
+				"Content-Length": {"20"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.rangeHeader != "" {
+			req.Header.Set("Range", tt.rangeHeader)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		resp := rec.Result()
+
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("%v: status = %d, want %d", tt.description, resp.StatusCode, tt.wantStatus)
+		}
+		if got := rec.Body.String(); got != tt.wantBody {
+			t.Errorf("%v: body = %q, want %q", tt.description, got, tt.wantBody)
+		}
+		for k, want := range tt.wantHeader {
+			if got := resp.Header.Values(k); !cmp.Equal(got, want) {
+				t.Errorf("%v: %s header = %q, want %q", tt.description, k, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache stores discovered webmention endpoints, keyed by target URL.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the cached endpoint for key, and whether it was found.
+	Get(key string) (string, bool)
+	// Set stores the endpoint for key.
+	Set(key, endpoint string)
+}
+
+// NewMemoryCache returns a Cache that stores endpoints in memory for the
+// duration of ttl.  A ttl of zero or less means entries never expire.
+func NewMemoryCache(ttl time.Duration) Cache {
+	return &memoryCache{ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+type memoryCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	endpoint string
+	expires  time.Time
+}
+
+func (m *memoryCache) Get(key string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return "", false
+	}
+	return e.endpoint, true
+}
+
+func (m *memoryCache) Set(key, endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := cacheEntry{endpoint: endpoint}
+	if m.ttl > 0 {
+		e.expires = time.Now().Add(m.ttl)
+	}
+	m.entries[key] = e
+}
@@ -0,0 +1,59 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithCache(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	var requests int
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Link", "</endpoint>; rel=webmention")
+	})
+
+	client := NewWithOptions(WithCache(NewMemoryCache(0)))
+	for i := 0; i < 2; i++ {
+		got, err := client.DiscoverEndpoint(server.URL)
+		if err != nil {
+			t.Errorf("DiscoverEndpoint returned error: %v", err)
+		}
+		if want := server.URL + "/endpoint"; got != want {
+			t.Errorf("DiscoverEndpoint returned %v, want %v", got, want)
+		}
+	}
+	if want := 1; requests != want {
+		t.Errorf("server received %d requests, want %d", requests, want)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache(0)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get on empty cache returned value")
+	}
+	c.Set("a", "b")
+	if got, ok := c.Get("a"); !ok || got != "b" {
+		t.Errorf("Get returned %q, %t; want %q, true", got, ok, "b")
+	}
+
+	c = NewMemoryCache(-1 * time.Second)
+	c.Set("a", "b")
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("Get returned no value for cache with no expiration")
+	}
+
+	c = NewMemoryCache(time.Nanosecond)
+	c.Set("a", "b")
+	time.Sleep(time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get returned expired value")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// command is a webmention subcommand.
+type command struct {
+	name  string
+	args  string // argument synopsis
+	short string // one line description
+	run   func(args []string)
+}
+
+// commands lists the available subcommands, in the order they are displayed
+// in usage text.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"discover", "<url>", "print the webmention endpoint for url", runDiscover},
+		{"links", "[flags] <url>", "list links on url that are candidates for webmentions", runLinks},
+		{"send", "[flags] <source> <target>", "send a single webmention from source to target", runSend},
+		{"verify", "[flags] <source> <target>", "verify that source links to target", runVerify},
+	}
+}
+
+// lookupCommand returns the subcommand with the given name, or nil if there
+// is none.
+func lookupCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// newFlagSet returns a FlagSet for the named subcommand.
+func newFlagSet(name string) *flag.FlagSet {
+	cmd := lookupCommand(name)
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "webmention %s: %s.\n\nUsage:\n\twebmention %s %s\n", cmd.name, cmd.short, cmd.name, cmd.args)
+		var hasFlags bool
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprint(os.Stderr, "\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parseArgs parses args into fs, exiting with usage text unless exactly n
+// positional arguments remain.  Each positional argument must be an absolute
+// URL.
+func parseArgs(fs *flag.FlagSet, args []string, n int) []string {
+	_ = fs.Parse(args)
+	if fs.NArg() != n {
+		fs.Usage()
+		os.Exit(2)
+	}
+	for _, a := range fs.Args() {
+		checkURL(a)
+	}
+	return fs.Args()
+}
+
+func runDiscover(args []string) {
+	fs := newFlagSet("discover")
+	detailed := fs.Bool("detailed", false, "print where the endpoint was found, redirects followed, and other advertised endpoints")
+	args = parseArgs(fs, args, 1)
+
+	d, err := client.DiscoverEndpointDetailed(args[0])
+	if err != nil {
+		fatalf("error discovering endpoint for %q: %v", args[0], err)
+	}
+	fmt.Println(d.Endpoint)
+	if !*detailed {
+		return
+	}
+
+	fmt.Printf("  source: %s", d.Source)
+	if d.Legacy {
+		fmt.Print(" (legacy rel)")
+	}
+	fmt.Printf("\n  url: %s\n", d.URL)
+	for _, r := range d.Redirects {
+		fmt.Printf("  redirect: %s -> %s (%d)\n", r.From, r.To, r.StatusCode)
+	}
+	for _, c := range d.Candidates {
+		fmt.Printf("  candidate: %s (%s)\n", c.Endpoint, c.Source)
+	}
+}
+
+func runLinks(args []string) {
+	fs := newFlagSet("links")
+	sel := fs.String("selector", ".h-entry", "CSS Selector limiting where to look for links")
+	args = parseArgs(fs, args, 1)
+
+	links, err := client.DiscoverLinks(args[0], *sel)
+	if err != nil {
+		fatalf("error discovering links for %q: %v", args[0], err)
+	}
+	for _, l := range links {
+		fmt.Println(l)
+	}
+}
+
+func runSend(args []string) {
+	fs := newFlagSet("send")
+	format := fs.String("format", "text", "output `format`: text, json, or jsonl")
+	dryRun := fs.Bool("dry-run", false, "discover the endpoint and report the planned webmention without sending it")
+	canonical := fs.Bool("canonical", false, "send the webmention to the canonical URL of target if it has permanently redirected")
+	pingback := fs.Bool("pingback", false, "send a pingback if target does not support webmention but supports pingback")
+	args = parseArgs(fs, args, 2)
+
+	rep, err := newReporter(*format, os.Stdout)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	r := sendWebmention(args[0], args[1], sendOptions{dryRun: *dryRun, canonical: *canonical, pingback: *pingback})
+	rep.report(r)
+	if err := rep.close(); err != nil {
+		fatalf("error writing results: %v", err)
+	}
+	if r.failed() {
+		os.Exit(1)
+	}
+}
+
+func runVerify(args []string) {
+	fs := newFlagSet("verify")
+	sel := fs.String("selector", "", "CSS Selector limiting where to look for links")
+	args = parseArgs(fs, args, 2)
+	source, target := args[0], args[1]
+
+	links, err := client.DiscoverLinks(source, *sel)
+	if err != nil {
+		fatalf("error discovering links for %q: %v", source, err)
+	}
+	for _, l := range links {
+		if l == target {
+			fmt.Printf("%s links to %s\n", source, target)
+			return
+		}
+	}
+	fatalf("%s does not link to %s", source, target)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// localPage is an HTML file in a local site directory.
+type localPage struct {
+	path string // path to the file on disk
+	url  string // canonical URL the file is served at
+}
+
+// localPages returns the HTML files in dir along with the URLs they are served
+// at, relative to baseURL.  Index files are mapped to the URL of their
+// directory.  If prevDir is non-empty, it is the output of a previous build of
+// the same site, and only files that are new or have changed since that build
+// are returned.
+func localPages(dir, baseURL, prevDir string) ([]localPage, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	var pages []localPage
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		if ext != ".html" && ext != ".htm" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if prevDir != "" {
+			changed, err := fileChanged(p, filepath.Join(prevDir, rel))
+			if err != nil {
+				return err
+			}
+			if !changed {
+				return nil
+			}
+		}
+
+		rel = filepath.ToSlash(rel)
+		if name := path.Base(rel); name == "index.html" || name == "index.htm" {
+			rel = strings.TrimSuffix(rel, name)
+		}
+		u := base.ResolveReference(&url.URL{Path: rel})
+		pages = append(pages, localPage{path: p, url: u.String()})
+		return nil
+	})
+	return pages, err
+}
+
+// fileChanged reports whether the contents of the file cur differ from the
+// file prev.  A missing prev file is considered changed.
+func fileChanged(cur, prev string) (bool, error) {
+	pb, err := os.ReadFile(prev)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	cb, err := os.ReadFile(cur)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(cb, pb), nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeFiles writes files, keyed by slash-separated path, under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLocalPages(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"index.html":           "home",
+		"about.htm":            "about",
+		"posts/index.html":     "posts",
+		"posts/a.html":         "a",
+		"posts/b/INDEX.HTML":   "b",
+		"posts/b/index.htm":    "b2",
+		"posts/c.HTML":         "c",
+		"style.css":            "css",
+		"img/photo.jpg":        "jpg",
+		"posts/draft.html.bak": "bak",
+	})
+
+	prev := t.TempDir()
+	writeFiles(t, prev, map[string]string{
+		"index.html":       "home",    // unchanged
+		"about.htm":        "about",   // unchanged
+		"posts/index.html": "old",     // changed
+		"posts/a.html":     "a",       // unchanged
+		"posts/c.HTML":     "c",       // unchanged
+		"gone.html":        "removed", // not in current build
+		// posts/b/* are missing from the previous build
+	})
+
+	all := []localPage{
+		{"about.htm", "https://example.com/blog/about.htm"},
+		{"index.html", "https://example.com/blog/"},
+		{"posts/a.html", "https://example.com/blog/posts/a.html"},
+		{"posts/b/INDEX.HTML", "https://example.com/blog/posts/b/INDEX.HTML"},
+		{"posts/b/index.htm", "https://example.com/blog/posts/b/"},
+		{"posts/c.HTML", "https://example.com/blog/posts/c.HTML"},
+		{"posts/index.html", "https://example.com/blog/posts/"},
+	}
+
+	tests := []struct {
+		description string
+		baseURL     string
+		prevDir     string
+		want        []localPage
+	}{
+		{"trailing slash", "https://example.com/blog/", "", all},
+		{"no trailing slash", "https://example.com/blog", "", all},
+		{"root", "https://example.com", "", []localPage{
+			{"about.htm", "https://example.com/about.htm"},
+			{"index.html", "https://example.com/"},
+			{"posts/a.html", "https://example.com/posts/a.html"},
+			{"posts/b/INDEX.HTML", "https://example.com/posts/b/INDEX.HTML"},
+			{"posts/b/index.htm", "https://example.com/posts/b/"},
+			{"posts/c.HTML", "https://example.com/posts/c.HTML"},
+			{"posts/index.html", "https://example.com/posts/"},
+		}},
+		{"previous build", "https://example.com/blog/", prev, []localPage{
+			{"posts/b/INDEX.HTML", "https://example.com/blog/posts/b/INDEX.HTML"},
+			{"posts/b/index.htm", "https://example.com/blog/posts/b/"},
+			{"posts/index.html", "https://example.com/blog/posts/"},
+		}},
+	}
+
+	for _, tt := range tests {
+		var want []localPage
+		for _, p := range tt.want {
+			want = append(want, localPage{path: filepath.Join(dir, filepath.FromSlash(p.path)), url: p.url})
+		}
+		got, err := localPages(dir, tt.baseURL, tt.prevDir)
+		if err != nil {
+			t.Fatalf("%v: localPages returned error: %v", tt.description, err)
+		}
+		if !cmp.Equal(got, want, cmp.AllowUnexported(localPage{})) {
+			t.Errorf("%v: localPages returned %v, want %v", tt.description, got, want)
+		}
+	}
+}
+
+func TestLocalPages_MissingDir(t *testing.T) {
+	if _, err := localPages(filepath.Join(t.TempDir(), "missing"), "https://example.com/", ""); err == nil {
+		t.Error("localPages returned no error for missing directory")
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// history records webmentions that have been sent, so that they can be
+// skipped on subsequent runs unless their source has changed.
+type history struct {
+	Sent []sentMention `json:"sent"`
+
+	index map[[2]string]int // index into Sent by source and target
+}
+
+// sentMention is a webmention that was successfully sent.
+type sentMention struct {
+	Source   string    `json:"source"`
+	Target   string    `json:"target"`
+	Endpoint string    `json:"endpoint"`
+	Status   int       `json:"status"`
+	Time     time.Time `json:"time"`
+	Hash     string    `json:"hash,omitempty"` // hash of the source content when sent
+}
+
+// defaultHistoryPath returns the default location of the history file, or an
+// empty string if there is none.
+func defaultHistoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "webmention", "history.json")
+}
+
+// contentHash returns the hash of source content b, as stored in history.
+func contentHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadHistory reads history from the named file.  If the file does not
+// exist, empty history is returned.
+func loadHistory(name string) (*history, error) {
+	h := new(history)
+	b, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// lookup returns the recorded webmention from source to target, or nil if
+// there is none.
+func (h *history) lookup(source, target string) *sentMention {
+	if h.index == nil {
+		h.index = make(map[[2]string]int)
+		for i, m := range h.Sent {
+			h.index[[2]string{m.Source, m.Target}] = i
+		}
+	}
+	if i, ok := h.index[[2]string{source, target}]; ok {
+		return &h.Sent[i]
+	}
+	return nil
+}
+
+// unsent returns the links that need a webmention sent: those with no
+// recorded webmention, and those whose source content has changed since the
+// webmention was sent.  Links with unknown source content are only compared
+// by source and target.
+func (h *history) unsent(links []link) []link {
+	var out []link
+	for _, l := range links {
+		m := h.lookup(l.source, l.url)
+		if m == nil || (l.hash != "" && l.hash != m.Hash) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// record records the successfully sent webmention described by r, whose
+// source content had the given hash.
+func (h *history) record(r result, hash string) {
+	m := sentMention{
+		Source:   r.Source,
+		Target:   r.Target,
+		Endpoint: r.Endpoint,
+		Status:   r.Status,
+		Time:     time.Now().UTC(),
+		Hash:     hash,
+	}
+	if existing := h.lookup(r.Source, r.Target); existing != nil {
+		*existing = m
+		return
+	}
+	h.index[[2]string{m.Source, m.Target}] = len(h.Sent)
+	h.Sent = append(h.Sent, m)
+}
+
+// save writes history to the named file, creating its directory if needed.
+// The file is replaced atomically, so an interrupted save does not lose
+// previously recorded history.
+func (h *history) save(name string) error {
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
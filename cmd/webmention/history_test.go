@@ -0,0 +1,140 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestHistory_Unsent(t *testing.T) {
+	h := &history{Sent: []sentMention{
+		{Source: "s1", Target: "t1", Hash: "h1"},
+		{Source: "s1", Target: "t2"}, // sent without a known hash
+	}}
+
+	tests := []struct {
+		description string
+		link        link
+		want        bool
+	}{
+		{"not recorded", link{source: "s1", url: "t3", hash: "h1"}, true},
+		{"other source", link{source: "s2", url: "t1", hash: "h1"}, true},
+		{"hash unchanged", link{source: "s1", url: "t1", hash: "h1"}, false},
+		{"hash changed", link{source: "s1", url: "t1", hash: "h2"}, true},
+		{"hash unknown", link{source: "s1", url: "t1"}, false},
+		{"recorded hash unknown", link{source: "s1", url: "t2", hash: "h1"}, true},
+		{"both hashes unknown", link{source: "s1", url: "t2"}, false},
+	}
+
+	for _, tt := range tests {
+		got := len(h.unsent([]link{tt.link})) == 1
+		if got != tt.want {
+			t.Errorf("%v: unsent(%v) returned link: %t, want %t", tt.description, tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestHistory_Record(t *testing.T) {
+	h := &history{Sent: []sentMention{
+		{Source: "s1", Target: "t1", Endpoint: "e1", Status: 202, Hash: "h1"},
+	}}
+
+	// replace existing entry
+	h.record(result{Source: "s1", Target: "t1", Endpoint: "e2", Status: 201}, "h2")
+	// add new entries
+	h.record(result{Source: "s1", Target: "t2", Endpoint: "e1", Status: 202}, "h2")
+	h.record(result{Source: "s2", Target: "t1", Endpoint: "e1", Status: 200}, "")
+
+	want := []sentMention{
+		{Source: "s1", Target: "t1", Endpoint: "e2", Status: 201, Hash: "h2"},
+		{Source: "s1", Target: "t2", Endpoint: "e1", Status: 202, Hash: "h2"},
+		{Source: "s2", Target: "t1", Endpoint: "e1", Status: 200},
+	}
+	if !cmp.Equal(h.Sent, want, cmpopts.IgnoreFields(sentMention{}, "Time")) {
+		t.Errorf("record produced %v, want %v", h.Sent, want)
+	}
+	for i, m := range h.Sent {
+		if m.Time.IsZero() {
+			t.Errorf("record did not set time for %v", m)
+		}
+		if got := h.lookup(m.Source, m.Target); got != &h.Sent[i] {
+			t.Errorf("lookup(%q, %q) returned %v, want %v", m.Source, m.Target, got, &h.Sent[i])
+		}
+	}
+
+	// updating an entry added by record keeps the index in sync
+	h.record(result{Source: "s2", Target: "t1", Endpoint: "e3", Status: 202}, "h3")
+	if len(h.Sent) != 3 {
+		t.Errorf("record added duplicate entry: %v", h.Sent)
+	}
+	if got := h.lookup("s2", "t1"); got == nil || got.Endpoint != "e3" || got.Hash != "h3" {
+		t.Errorf("lookup after record returned %v", got)
+	}
+}
+
+func TestHistory_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "webmention", "history.json")
+
+	// missing file is empty history
+	h, err := loadHistory(name)
+	if err != nil {
+		t.Fatalf("loadHistory returned error: %v", err)
+	}
+	if len(h.Sent) != 0 || h.lookup("s1", "t1") != nil {
+		t.Errorf("loadHistory of missing file returned %v, want empty history", h.Sent)
+	}
+
+	h.record(result{Source: "s1", Target: "t1", Endpoint: "e1", Status: 202}, "h1")
+	h.record(result{Source: "s1", Target: "t2", Endpoint: "e2", Status: 201}, "")
+	if err := h.save(name); err != nil {
+		t.Fatalf("save returned error: %v", err)
+	}
+
+	got, err := loadHistory(name)
+	if err != nil {
+		t.Fatalf("loadHistory returned error: %v", err)
+	}
+	if !cmp.Equal(got.Sent, h.Sent) {
+		t.Errorf("loadHistory returned %v, want %v", got.Sent, h.Sent)
+	}
+	if m := got.lookup("s1", "t2"); m == nil || m.Endpoint != "e2" {
+		t.Errorf("lookup on loaded history returned %v", m)
+	}
+
+	// saving again replaces the file and leaves no temporary files behind
+	got.record(result{Source: "s2", Target: "t1", Endpoint: "e1", Status: 202}, "h2")
+	if err := got.save(name); err != nil {
+		t.Fatalf("save returned error: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "history.json" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("history directory contains %v, want only history.json", names)
+	}
+	if h, err := loadHistory(name); err != nil || len(h.Sent) != 3 {
+		t.Errorf("loadHistory after second save returned %v, %v; want 3 entries", h, err)
+	}
+}
+
+func TestLoadHistory_Invalid(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "history.json")
+	if err := os.WriteFile(name, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadHistory(name); err == nil {
+		t.Error("loadHistory returned no error for invalid file")
+	}
+}
@@ -2,17 +2,24 @@
 // SPDX-License-Identifier: BSD-3-Clause
 
 // The webmention binary is a command line utility for sending webmentions to
-// the URLs linked to by a given webpage.
+// the URLs linked to by a given webpage, along with subcommands for
+// discovering endpoints and links, sending single webmentions, and verifying
+// that a source links to a target.
 package main
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/wsxiaoys/terminal/color"
 	"willnorris.com/go/webmention"
@@ -22,6 +29,38 @@ const usageText = `webmention is a tool for sending webmentions.
 
 Usage:
 	webmention [flags] <url>
+	webmention -dir <path> [flags] <base url>
+	webmention <command> [arguments]
+
+Links are discovered from <url> and presented for interactive selection.  Use
+-all, -include, and -exclude to preselect links, and -yes to send to the
+selected links without prompting.  Use -targets to read target URLs from a file
+rather than discovering them.  Use -dry-run to discover endpoints and report
+which webmentions would be sent without sending them.
+
+Use -feed to treat <url> as a sitemap, RSS feed, Atom feed, or JSON Feed.  Links
+are discovered from every entry it lists, and webmentions are sent with each
+entry as the source.
+
+Use -dir to discover links from the HTML files in a local directory, such as the
+output of a static site generator.  Each file is mapped to a URL relative to
+<base url>, which is used as the source.  Use -prev with the output of a
+previous build to only consider files that are new or have changed.
+
+Sent webmentions are recorded in a history file (see -history).  By default,
+webmentions are only sent for links that are not in the history, or whose
+source has changed since the webmention was sent.  Use -force to send
+webmentions regardless of history.
+
+Use -format to select how results are reported: "text" for human readable
+output, "json" for a single JSON array, or "jsonl" for one JSON object per line.
+When using json or jsonl, all other output is written to stderr.
+
+webmention exits with a non-zero status if sending to any target failed.
+
+Commands:
+%s
+Run "webmention <command> -h" for help on a command.
 
 Flags:
 `
@@ -30,65 +69,284 @@ var (
 	client *webmention.Client
 	input  string
 
-	selector = flag.String("selector", ".h-entry", "CSS Selector limiting where to look for links")
+	// msgs is where informational messages and prompts are written.
+	msgs io.Writer = os.Stdout
+
+	selector    = flag.String("selector", ".h-entry", "CSS Selector limiting where to look for links")
+	all         = flag.Bool("all", false, "select all links")
+	include     = flag.String("include", "", "select links matching this regular expression")
+	exclude     = flag.String("exclude", "", "deselect links matching this regular expression")
+	yes         = flag.Bool("yes", false, "send webmentions to selected links without prompting")
+	targets     = flag.String("targets", "", "read target URLs from `file`, one per line, instead of discovering links.  Use - to read from stdin (implies -yes)")
+	format      = flag.String("format", "text", "output `format`: text, json, or jsonl")
+	pingback    = flag.Bool("pingback", false, "send a pingback to targets that do not support webmention but support pingback")
+	canonical   = flag.Bool("canonical", false, "send webmentions to the canonical URL of targets that have permanently redirected")
+	dryRun      = flag.Bool("dry-run", false, "discover endpoints and report planned webmentions without sending them")
+	feed        = flag.Bool("feed", false, "treat url as a sitemap, RSS feed, Atom feed, or JSON Feed and discover links from each entry")
+	historyPath = flag.String("history", defaultHistoryPath(), "record sent webmentions in `file`.  Set to empty to disable history")
+	force       = flag.Bool("force", false, "send webmentions even if already recorded in history")
+	dir         = flag.String("dir", "", "discover links from HTML files in local `directory` rather than fetching url")
+	prevDir     = flag.String("prev", "", "with -dir, only consider files that are new or changed since the previous build in `directory`")
 )
 
 func main() {
-	flag.Parse()
 	flag.Usage = func() {
-		fmt.Fprint(os.Stderr, usageText)
+		var cmds strings.Builder
+		for _, c := range commands {
+			fmt.Fprintf(&cmds, "\t%-10s %s\n", c.name, c.short)
+		}
+		fmt.Fprintf(os.Stderr, usageText, cmds.String())
 		flag.PrintDefaults()
 	}
 
 	client = webmention.New(nil)
+	if len(os.Args) > 1 {
+		if cmd := lookupCommand(os.Args[1]); cmd != nil {
+			cmd.run(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Parse()
 	input = flag.Arg(0)
 	if input == "" {
 		flag.Usage()
 		return
 	}
+	checkURL(input)
 
-	if u, err := url.Parse(input); err != nil {
-		fatalf("Not a valid URL: %q", input)
-	} else if !u.IsAbs() {
-		fatalf("URL %q is not an absolute URL", input)
+	rep, err := newReporter(*format, os.Stdout)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if *format != "text" {
+		msgs = os.Stderr
 	}
 
-	fmt.Printf("Searching for links from %q to send webmentions to...\n\n", input)
-	dl, err := client.DiscoverLinks(input, *selector)
-	if err != nil {
-		fatalf("error discovering links for %q: %v", input, err)
+	var includeRE, excludeRE *regexp.Regexp
+	if *include != "" {
+		includeRE, err = regexp.Compile(*include)
+		if err != nil {
+			fatalf("invalid -include pattern: %v", err)
+		}
+	}
+	if *exclude != "" {
+		excludeRE, err = regexp.Compile(*exclude)
+		if err != nil {
+			fatalf("invalid -exclude pattern: %v", err)
+		}
+	}
+
+	var modes int
+	for _, m := range []bool{*targets != "", *feed, *dir != ""} {
+		if m {
+			modes++
+		}
+	}
+	if modes > 1 {
+		fatalf("only one of -targets, -feed, and -dir may be specified")
 	}
+	if *prevDir != "" && *dir == "" {
+		fatalf("-prev requires -dir")
+	}
+
 	var links []link
-	for _, l := range dl {
-		links = append(links, link{url: l})
+	if *targets != "" {
+		tl, err := readTargets(*targets)
+		if err != nil {
+			fatalf("error reading targets from %q: %v", *targets, err)
+		}
+		// explicitly listed targets are selected by default
+		for _, l := range tl {
+			links = append(links, link{source: input, url: l, ping: true})
+		}
+	} else if *feed {
+		fmt.Fprintf(msgs, "Searching for entries in %q...\n\n", input)
+		entries, err := client.DiscoverEntries(input)
+		if err != nil {
+			fatalf("error discovering entries for %q: %v", input, err)
+		}
+		for _, e := range entries {
+			dl, hash, err := discoverLinks(e)
+			if err != nil {
+				errorf("error discovering links for %q: %v", e, err)
+				continue
+			}
+			for _, l := range dl {
+				links = append(links, link{source: e, url: l, hash: hash})
+			}
+		}
+	} else if *dir != "" {
+		fmt.Fprintf(msgs, "Searching for links in %q to send webmentions to...\n\n", *dir)
+		pages, err := localPages(*dir, input, *prevDir)
+		if err != nil {
+			fatalf("error reading %q: %v", *dir, err)
+		}
+		for _, p := range pages {
+			dl, hash, err := discoverLocalLinks(p)
+			if err != nil {
+				errorf("error discovering links in %q: %v", p.path, err)
+				continue
+			}
+			for _, l := range dl {
+				links = append(links, link{source: p.url, url: l, hash: hash})
+			}
+		}
+	} else {
+		fmt.Fprintf(msgs, "Searching for links from %q to send webmentions to...\n\n", input)
+		dl, hash, err := discoverLinks(input)
+		if err != nil {
+			fatalf("error discovering links for %q: %v", input, err)
+		}
+		for _, l := range dl {
+			links = append(links, link{source: input, url: l, hash: hash})
+		}
+	}
+
+	var hist *history
+	if *historyPath != "" {
+		hist, err = loadHistory(*historyPath)
+		if err != nil {
+			fatalf("error reading history from %q: %v", *historyPath, err)
+		}
+		if !*force {
+			n := len(links)
+			links = hist.unsent(links)
+			if skipped := n - len(links); skipped > 0 {
+				fmt.Fprintf(msgs, "Skipping %d previously sent webmentions (use -force to resend)\n\n", skipped)
+			}
+		}
+	}
+
+	preselectLinks(links, *all, includeRE, excludeRE)
+	if !*yes && *targets != "-" {
+		selectLinks(links)
+	}
+	failed := sendWebmentions(rep, hist, links)
+	if hist != nil && !*dryRun {
+		if err := hist.save(*historyPath); err != nil {
+			fatalf("error writing history to %q: %v", *historyPath, err)
+		}
 	}
+	if err := rep.close(); err != nil {
+		fatalf("error writing results: %v", err)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// discoverLinks fetches source and discovers the links in it, returning the
+// links and a hash of the source content.
+func discoverLinks(source string) ([]string, string, error) {
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if code := resp.StatusCode; code < 200 || 300 <= code {
+		return nil, "", fmt.Errorf("response error: %v", resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	links, err := webmention.DiscoverLinksFromReader(bytes.NewReader(b), source, *selector)
+	return links, contentHash(b), err
+}
+
+// discoverLocalLinks discovers links in the local page p, returning the links
+// and a hash of the page content.
+func discoverLocalLinks(p localPage) ([]string, string, error) {
+	b, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, "", err
+	}
+	links, err := webmention.DiscoverLinksFromReader(bytes.NewReader(b), p.url, *selector)
+	return links, contentHash(b), err
+}
 
-	selectLinks(links)
-	sendWebmentions(links)
+// checkURL exits with an error if s is not a valid absolute URL.
+func checkURL(s string) {
+	if u, err := url.Parse(s); err != nil {
+		fatalf("Not a valid URL: %q", s)
+	} else if !u.IsAbs() {
+		fatalf("URL %q is not an absolute URL", s)
+	}
 }
 
 type link struct {
-	url  string
-	ping bool
+	source string
+	url    string
+	hash   string // hash of the source content, if known
+	ping   bool
+}
+
+// readTargets reads target URLs from the named file, one per line.  If name
+// is "-", URLs are read from stdin.  Blank lines and lines beginning with '#'
+// are ignored.
+func readTargets(name string) ([]string, error) {
+	f := os.Stdin
+	if name != "-" {
+		var err error
+		f, err = os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+	}
+
+	var urls []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, scanner.Err()
+}
+
+// preselectLinks updates the selection state of links.  If all is true, every
+// link is selected.  Links matching include are then selected, and links
+// matching exclude are deselected.
+func preselectLinks(links []link, all bool, include, exclude *regexp.Regexp) {
+	for i, l := range links {
+		if all || (include != nil && include.MatchString(l.url)) {
+			links[i].ping = true
+		}
+		if exclude != nil && exclude.MatchString(l.url) {
+			links[i].ping = false
+		}
+	}
 }
 
 func selectLinks(links []link) {
 	reader := bufio.NewReader(os.Stdin)
 
 	for {
-		fmt.Println("Select links to send webmentions to:")
+		fmt.Fprintln(msgs, "Select links to send webmentions to:")
 		for i, link := range links {
 			x := " "
 			if link.ping {
 				x = "x"
 			}
-			fmt.Printf("  [%s]: %2d. %v\n", x, i, link.url)
+			if link.source != input {
+				fmt.Fprintf(msgs, "  [%s]: %2d. %v (from %v)\n", x, i, link.url, link.source)
+			} else {
+				fmt.Fprintf(msgs, "  [%s]: %2d. %v\n", x, i, link.url)
+			}
 		}
 
-		fmt.Print("\nEnter space separated IDs of links to toggle, [a]ll or [n]one: ")
+		fmt.Fprint(msgs, "\nEnter space separated IDs of links to toggle, [a]ll or [n]one: ")
 		input, _ := reader.ReadString('\n')
 		input = strings.ToLower(strings.TrimSpace(input))
-		fmt.Println()
+		fmt.Fprintln(msgs)
 
 		switch input {
 		case "":
@@ -113,30 +371,115 @@ func selectLinks(links []link) {
 	}
 }
 
-func sendWebmentions(links []link) {
-	fmt.Println("Sending webmentions...")
+// sendWebmentions sends webmentions to all selected links, reporting each
+// result to rep and recording successful sends in hist, if non-nil.  It
+// returns true if sending to any link failed.
+func sendWebmentions(rep reporter, hist *history, links []link) bool {
+	var failed bool
 	for _, l := range links {
 		if !l.ping {
 			continue
 		}
+		r := sendWebmention(l.source, l.url, sendOptions{dryRun: *dryRun, canonical: *canonical, pingback: *pingback})
+		rep.report(r)
+		if hist != nil && !r.DryRun && r.ErrorClass == "" {
+			hist.record(r, l.hash)
+		}
+		failed = failed || r.failed()
+	}
+	return failed
+}
 
-		fmt.Printf("  %v ... ", l.url)
-		endpoint, err := client.DiscoverEndpoint(l.url)
-		if err != nil {
-			errorf("%v", err)
-			continue
-		} else if endpoint == "" {
-			_, _ = color.Println("@{!r}no webmention support@|")
-			continue
+// sendOptions configures how sendWebmention sends a webmention.
+type sendOptions struct {
+	dryRun    bool // discover the endpoint, but do not send
+	canonical bool // send to the target's canonical URL after permanent redirects
+	pingback  bool // send a pingback if the target has no webmention endpoint
+}
+
+// sendWebmention discovers the webmention endpoint for target and sends a
+// webmention from source.
+func sendWebmention(source, target string, opts sendOptions) result {
+	r := result{Source: source, Target: target, DryRun: opts.dryRun}
+
+	start := time.Now()
+	d, err := client.DiscoverEndpointDetailed(target)
+	r.DiscoveryMS = time.Since(start).Milliseconds()
+	if errors.Is(err, webmention.ErrNoEndpointFound) && opts.pingback {
+		return sendPingback(r, opts)
+	}
+	if errors.Is(err, webmention.ErrNoEndpointFound) || (err == nil && d.Endpoint == "") {
+		r.ErrorClass = errClassNoEndpoint
+		r.Error = webmention.ErrNoEndpointFound.Error()
+		return r
+	} else if err != nil {
+		r.ErrorClass = errClassDiscovery
+		r.Error = err.Error()
+		return r
+	}
+	endpoint := d.Endpoint
+	r.Endpoint = endpoint
+	if opts.canonical {
+		if u := d.CanonicalURL(); u != target {
+			r.CanonicalTarget = u
+			target = u
 		}
+	}
+	if opts.dryRun {
+		return r
+	}
 
-		_, err = client.SendWebmention(endpoint, input, l.url)
-		if err != nil {
-			errorf("%v", err)
-			continue
+	start = time.Now()
+	resp, err := client.SendWebmention(endpoint, source, target)
+	r.SendMS = time.Since(start).Milliseconds()
+	if resp != nil {
+		r.Status = resp.StatusCode
+		r.Location = resp.Header.Get("Location")
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		r.ErrorClass = errClassSend
+		if resp != nil {
+			r.ErrorClass = errClassStatus
+		}
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// sendPingback discovers the pingback server for r.Target and sends a
+// pingback from r.Source, updating r with the outcome.
+func sendPingback(r result, opts sendOptions) result {
+	start := time.Now()
+	server, err := client.DiscoverPingback(r.Target)
+	r.DiscoveryMS += time.Since(start).Milliseconds()
+	if errors.Is(err, webmention.ErrNoEndpointFound) {
+		r.ErrorClass = errClassNoEndpoint
+		r.Error = err.Error()
+		return r
+	} else if err != nil {
+		r.ErrorClass = errClassDiscovery
+		r.Error = err.Error()
+		return r
+	}
+	r.Endpoint = server
+	r.Pingback = true
+	if opts.dryRun {
+		return r
+	}
+
+	start = time.Now()
+	err = client.SendPingback(server, r.Source, r.Target)
+	r.SendMS = time.Since(start).Milliseconds()
+	if err != nil {
+		r.ErrorClass = errClassSend
+		var fault *webmention.PingbackFault
+		if errors.As(err, &fault) {
+			r.ErrorClass = errClassFault
 		}
-		_, _ = color.Println("@gsent@|")
+		r.Error = err.Error()
 	}
+	return r
 }
 
 func fatalf(format string, args ...interface{}) {
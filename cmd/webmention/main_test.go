@@ -0,0 +1,112 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadTargets(t *testing.T) {
+	content := `# targets to mention
+https://example.com/a
+
+  https://example.com/b
+	# indented comment
+https://example.com/c#fragment
+`
+	want := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c#fragment"}
+
+	name := filepath.Join(t.TempDir(), "targets.txt")
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readTargets(name)
+	if err != nil {
+		t.Fatalf("readTargets returned error: %v", err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("readTargets returned %q, want %q", got, want)
+	}
+
+	// "-" reads from stdin
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	stdin := os.Stdin
+	os.Stdin = f
+	defer func() {
+		os.Stdin = stdin
+	}()
+	got, err = readTargets("-")
+	if err != nil {
+		t.Fatalf("readTargets(%q) returned error: %v", "-", err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("readTargets(%q) returned %q, want %q", "-", got, want)
+	}
+
+	if _, err := readTargets(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("readTargets returned no error for missing file")
+	}
+}
+
+func TestPreselectLinks(t *testing.T) {
+	urls := []string{
+		"https://example.com/a",
+		"https://example.com/b",
+		"https://example.org/a",
+		"https://example.org/b",
+	}
+
+	tests := []struct {
+		description string
+		all         bool
+		include     string
+		exclude     string
+		want        []string // selected urls
+	}{
+		{"none", false, "", "", nil},
+		{"all", true, "", "", urls},
+		{"include", false, "example\\.com", "", urls[:2]},
+		{"exclude", false, "", "/a$", nil},
+		{"all exclude", true, "", "/a$", []string{urls[1], urls[3]}},
+		{"include exclude", false, "example\\.com", "/b$", urls[:1]},
+		{"exclude wins", false, "example", "example", nil},
+	}
+
+	for _, tt := range tests {
+		var links []link
+		for _, u := range urls {
+			links = append(links, link{source: "s", url: u})
+		}
+		var include, exclude *regexp.Regexp
+		if tt.include != "" {
+			include = regexp.MustCompile(tt.include)
+		}
+		if tt.exclude != "" {
+			exclude = regexp.MustCompile(tt.exclude)
+		}
+
+		preselectLinks(links, tt.all, include, exclude)
+		var got []string
+		for _, l := range links {
+			if l.ping {
+				got = append(got, l.url)
+			}
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("%v: preselectLinks selected %v, want %v", tt.description, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/wsxiaoys/terminal/color"
+)
+
+// Error classes reported in a result.
+const (
+	errClassDiscovery  = "discovery"   // endpoint discovery failed
+	errClassNoEndpoint = "no_endpoint" // target does not advertise an endpoint
+	errClassSend       = "send"        // request to the endpoint failed
+	errClassStatus     = "status"      // endpoint returned a non-2xx status
+	errClassFault      = "fault"       // pingback server returned a fault
+)
+
+// result is the outcome of sending a webmention to a single target.
+type result struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// CanonicalTarget is the target URL the webmention was sent to, if it
+	// differs from Target because Target has permanently redirected.
+	CanonicalTarget string `json:"canonical_target,omitempty"`
+	Endpoint        string `json:"endpoint,omitempty"`
+	Status          int    `json:"status,omitempty"`
+	Location        string `json:"location,omitempty"`
+	ErrorClass      string `json:"error_class,omitempty"`
+	Error           string `json:"error,omitempty"`
+	DiscoveryMS     int64  `json:"discovery_ms"`
+	SendMS          int64  `json:"send_ms"`
+	DryRun          bool   `json:"dry_run,omitempty"`
+	Pingback        bool   `json:"pingback,omitempty"` // sent as a pingback rather than a webmention
+}
+
+// failed reports whether r represents a failure.  Targets with no webmention
+// endpoint are not considered failures.
+func (r result) failed() bool {
+	return r.ErrorClass != "" && r.ErrorClass != errClassNoEndpoint
+}
+
+// reporter writes results in a particular output format.
+type reporter interface {
+	report(result)
+	close() error
+}
+
+// newReporter returns a reporter for the named format, writing to w.
+func newReporter(format string, w io.Writer) (reporter, error) {
+	switch format {
+	case "text":
+		return &textReporter{w: w}, nil
+	case "json":
+		return &jsonReporter{w: w}, nil
+	case "jsonl":
+		return &jsonlReporter{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// textReporter writes colored, human readable results.
+type textReporter struct {
+	w       io.Writer
+	started bool
+}
+
+func (t *textReporter) report(r result) {
+	if !t.started {
+		if r.DryRun {
+			fmt.Fprintln(t.w, "Discovering endpoints (dry run)...")
+		} else {
+			fmt.Fprintln(t.w, "Sending webmentions...")
+		}
+		t.started = true
+	}
+	fmt.Fprintf(t.w, "  %v ... ", r.Target)
+	switch {
+	case r.ErrorClass == "" && r.DryRun && r.Pingback:
+		_, _ = color.Fprintf(t.w, "@gwould send pingback to@| %s\n", r.Endpoint)
+	case r.ErrorClass == "" && r.DryRun:
+		_, _ = color.Fprintf(t.w, "@gwould send to@| %s\n", r.Endpoint)
+	case r.ErrorClass == "" && r.Pingback:
+		_, _ = color.Fprintln(t.w, "@gsent pingback@|")
+	case r.ErrorClass == "":
+		_, _ = color.Fprintln(t.w, "@gsent@|")
+	case r.ErrorClass == errClassNoEndpoint:
+		_, _ = color.Fprintln(t.w, "@{!r}no webmention support@|")
+	default:
+		_, _ = color.Fprintf(t.w, "@{!r}ERROR:@| %s\n", r.Error)
+	}
+}
+
+func (t *textReporter) close() error { return nil }
+
+// jsonReporter writes all results as a single JSON array when closed.
+type jsonReporter struct {
+	w       io.Writer
+	results []result
+}
+
+func (j *jsonReporter) report(r result) {
+	j.results = append(j.results, r)
+}
+
+func (j *jsonReporter) close() error {
+	if j.results == nil {
+		j.results = []result{}
+	}
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(j.results)
+}
+
+// jsonlReporter writes each result as a JSON object on its own line.
+type jsonlReporter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlReporter) report(r result) {
+	_ = j.enc.Encode(r)
+}
+
+func (j *jsonlReporter) close() error { return nil }
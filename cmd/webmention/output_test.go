@@ -0,0 +1,197 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewReporter(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    reporter
+		wantErr bool
+	}{
+		{"text", &textReporter{}, false},
+		{"json", &jsonReporter{}, false},
+		{"jsonl", &jsonlReporter{}, false},
+		{"", nil, true},
+		{"JSON", nil, true},
+		{"xml", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := newReporter(tt.format, new(bytes.Buffer))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newReporter(%q) returned error %v, want error: %t", tt.format, err, tt.wantErr)
+		}
+		if err != nil && !strings.Contains(err.Error(), "unknown format") {
+			t.Errorf("newReporter(%q) returned error %q, want unknown format error", tt.format, err)
+		}
+		if gotType, wantType := fmt.Sprintf("%T", got), fmt.Sprintf("%T", tt.want); gotType != wantType {
+			t.Errorf("newReporter(%q) returned %s, want %s", tt.format, gotType, wantType)
+		}
+	}
+}
+
+var testResults = []result{
+	{
+		Source:      "https://example.com/post",
+		Target:      "https://example.org/a",
+		Endpoint:    "https://example.org/webmention",
+		Status:      202,
+		Location:    "https://example.org/status/1",
+		DiscoveryMS: 12,
+		SendMS:      34,
+	},
+	{
+		Source:     "https://example.com/post",
+		Target:     "https://example.net/b",
+		ErrorClass: errClassNoEndpoint,
+		Error:      "no endpoint found",
+	},
+}
+
+// wantResults are testResults as JSON objects.  Empty optional fields are
+// omitted, while timings are always present.
+var wantResults = []map[string]any{
+	{
+		"source":       "https://example.com/post",
+		"target":       "https://example.org/a",
+		"endpoint":     "https://example.org/webmention",
+		"status":       float64(202),
+		"location":     "https://example.org/status/1",
+		"discovery_ms": float64(12),
+		"send_ms":      float64(34),
+	},
+	{
+		"source":       "https://example.com/post",
+		"target":       "https://example.net/b",
+		"error_class":  "no_endpoint",
+		"error":        "no endpoint found",
+		"discovery_ms": float64(0),
+		"send_ms":      float64(0),
+	},
+}
+
+func TestJSONReporter(t *testing.T) {
+	// no results is an empty array, not null
+	var buf bytes.Buffer
+	rep, _ := newReporter("json", &buf)
+	if err := rep.close(); err != nil {
+		t.Fatalf("close returned error: %v", err)
+	}
+	if got, want := strings.TrimSpace(buf.String()), "[]"; got != want {
+		t.Errorf("empty json output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	rep, _ = newReporter("json", &buf)
+	for _, r := range testResults {
+		rep.report(r)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("json reporter wrote output before close: %q", buf.String())
+	}
+	if err := rep.close(); err != nil {
+		t.Fatalf("close returned error: %v", err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json output %q is not valid JSON: %v", buf.String(), err)
+	}
+	if !cmp.Equal(got, wantResults) {
+		t.Errorf("json output = %v, want %v", got, wantResults)
+	}
+}
+
+func TestJSONLReporter(t *testing.T) {
+	var buf bytes.Buffer
+	rep, _ := newReporter("jsonl", &buf)
+	for i, r := range testResults {
+		rep.report(r)
+		// each result is written as soon as it is reported
+		if got, want := strings.Count(buf.String(), "\n"), i+1; got != want {
+			t.Errorf("jsonl output has %d lines after %d results, want %d", got, i+1, want)
+		}
+	}
+	if err := rep.close(); err != nil {
+		t.Fatalf("close returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(wantResults) {
+		t.Fatalf("jsonl output has %d lines, want %d: %q", len(lines), len(wantResults), buf.String())
+	}
+	for i, line := range lines {
+		var got map[string]any
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Errorf("jsonl line %q is not valid JSON: %v", line, err)
+			continue
+		}
+		if !cmp.Equal(got, wantResults[i]) {
+			t.Errorf("jsonl line %d = %v, want %v", i, got, wantResults[i])
+		}
+	}
+}
+
+func TestResult_JSON(t *testing.T) {
+	r := result{
+		Source:          "s",
+		Target:          "t",
+		CanonicalTarget: "c",
+		Endpoint:        "e",
+		Status:          201,
+		Location:        "l",
+		ErrorClass:      errClassStatus,
+		Error:           "response error: 500",
+		DiscoveryMS:     1,
+		SendMS:          2,
+		DryRun:          true,
+		Pingback:        true,
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	want := `{"source":"s","target":"t","canonical_target":"c","endpoint":"e","status":201,` +
+		`"location":"l","error_class":"status","error":"response error: 500",` +
+		`"discovery_ms":1,"send_ms":2,"dry_run":true,"pingback":true}`
+	if got := string(b); got != want {
+		t.Errorf("json.Marshal(result) = %s, want %s", got, want)
+	}
+
+	b, err = json.Marshal(result{Source: "s", Target: "t"})
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	want = `{"source":"s","target":"t","discovery_ms":0,"send_ms":0}`
+	if got := string(b); got != want {
+		t.Errorf("json.Marshal(empty result) = %s, want %s", got, want)
+	}
+}
+
+func TestResult_Failed(t *testing.T) {
+	tests := []struct {
+		errorClass string
+		want       bool
+	}{
+		{"", false},
+		{errClassNoEndpoint, false},
+		{errClassDiscovery, true},
+		{errClassSend, true},
+		{errClassStatus, true},
+		{errClassFault, true},
+	}
+	for _, tt := range tests {
+		if got := (result{ErrorClass: tt.errorClass}).failed(); got != tt.want {
+			t.Errorf("result{ErrorClass: %q}.failed() = %t, want %t", tt.errorClass, got, tt.want)
+		}
+	}
+}
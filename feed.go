@@ -0,0 +1,220 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// maxSitemapDepth is the maximum depth of nested sitemap indexes that
+// DiscoverEntries will follow.
+const maxSitemapDepth = 2
+
+// DiscoverEntries discovers the URLs of the entries listed in the sitemap, RSS
+// feed, Atom feed, or JSON Feed at urlStr.  These are candidates for passing
+// to DiscoverLinks to find URLs to send webmentions to.  Sitemap indexes are
+// followed, and the entries of each referenced sitemap are returned.
+func (c *Client) DiscoverEntries(urlStr string) ([]string, error) {
+	return c.discoverEntries(urlStr, 0)
+}
+
+func (c *Client) discoverEntries(urlStr string, depth int) ([]string, error) {
+	resp, err := c.get(context.Background(), urlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if code := resp.StatusCode; code < 200 || 300 <= code {
+		return nil, fmt.Errorf("response error: %v", resp.StatusCode)
+	}
+
+	entries, sitemaps, err := parseEntries(c.limitBody(resp.Body))
+	if err != nil {
+		return nil, err
+	}
+	urls, err := resolveReferences(resp.Request.URL.String(), entries...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sitemaps) > 0 && depth < maxSitemapDepth {
+		sitemaps, err = resolveReferences(resp.Request.URL.String(), sitemaps...)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sitemaps {
+			u, err := c.discoverEntries(s, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			urls = append(urls, u...)
+		}
+	}
+	return urls, nil
+}
+
+// DiscoverEntriesFromReader discovers the URLs of the entries listed in the
+// sitemap, RSS feed, Atom feed, or JSON Feed read from 'r'.  Relative URLs are
+// resolved against 'baseURL'.  Sitemaps listed in a sitemap index are not
+// followed.
+func DiscoverEntriesFromReader(r io.Reader, baseURL string) ([]string, error) {
+	entries, _, err := parseEntries(r)
+	if err != nil {
+		return nil, err
+	}
+	return resolveReferences(baseURL, entries...)
+}
+
+// parseEntries parses r as a sitemap, RSS feed, Atom feed, or JSON Feed and
+// returns the URLs of the entries it lists.  If r is a sitemap index, the URLs
+// of the sitemaps it lists are returned as sitemaps.
+func parseEntries(r io.Reader) (entries, sitemaps []string, err error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if b = bytes.TrimSpace(b); bytes.HasPrefix(b, []byte("{")) {
+		entries, err = parseJSONFeed(b)
+		return entries, nil, err
+	}
+
+	root, err := xmlRoot(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch root.Local {
+	case "urlset":
+		var doc struct {
+			URLs []struct {
+				Loc string `xml:"loc"`
+			} `xml:"url"`
+		}
+		err = unmarshalXML(b, &doc)
+		for _, u := range doc.URLs {
+			entries = append(entries, u.Loc)
+		}
+	case "sitemapindex":
+		var doc struct {
+			Sitemaps []struct {
+				Loc string `xml:"loc"`
+			} `xml:"sitemap"`
+		}
+		err = unmarshalXML(b, &doc)
+		for _, s := range doc.Sitemaps {
+			sitemaps = append(sitemaps, s.Loc)
+		}
+	case "rss":
+		var doc struct {
+			Items []struct {
+				Link string `xml:"link"`
+			} `xml:"channel>item"`
+		}
+		err = unmarshalXML(b, &doc)
+		for _, i := range doc.Items {
+			entries = append(entries, i.Link)
+		}
+	case "RDF": // RSS 1.0
+		var doc struct {
+			Items []struct {
+				Link string `xml:"link"`
+			} `xml:"item"`
+		}
+		err = unmarshalXML(b, &doc)
+		for _, i := range doc.Items {
+			entries = append(entries, i.Link)
+		}
+	case "feed": // Atom
+		var doc struct {
+			Entries []struct {
+				Links []struct {
+					Href string `xml:"href,attr"`
+					Rel  string `xml:"rel,attr"`
+				} `xml:"link"`
+			} `xml:"entry"`
+		}
+		err = unmarshalXML(b, &doc)
+		for _, e := range doc.Entries {
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					entries = append(entries, l.Href)
+					break
+				}
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported document type %q", root.Local)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return trimEmpty(entries), trimEmpty(sitemaps), nil
+}
+
+// parseJSONFeed returns the URLs of the items in the JSON Feed b.
+func parseJSONFeed(b []byte) ([]string, error) {
+	var feed struct {
+		Items []struct {
+			URL string `json:"url"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(b, &feed); err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, i := range feed.Items {
+		urls = append(urls, i.URL)
+	}
+	return trimEmpty(urls), nil
+}
+
+// xmlRoot returns the name of the root element of the XML document b.
+func xmlRoot(b []byte) (xml.Name, error) {
+	d := newXMLDecoder(b)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name, nil
+		}
+	}
+}
+
+// unmarshalXML parses the XML document b and stores the result in v.
+func unmarshalXML(b []byte, v any) error {
+	return newXMLDecoder(b).Decode(v)
+}
+
+// newXMLDecoder returns a decoder for the XML document b that supports the
+// character encodings that may be declared by feeds and sitemaps, such as
+// ISO-8859-1 or windows-1252.
+func newXMLDecoder(b []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	d.CharsetReader = charset.NewReaderLabel
+	return d
+}
+
+// trimEmpty returns the non-empty values in s, with surrounding whitespace
+// removed.
+func trimEmpty(s []string) []string {
+	var out []string
+	for _, v := range s {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
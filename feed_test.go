@@ -0,0 +1,108 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiscoverEntriesFromReader(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		// sitemap
+		{`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>http://example.com/a</loc></url>
+  <url><loc> /b </loc><lastmod>2020-01-01</lastmod></url>
+</urlset>`, []string{"http://example.com/a", "http://example.com/b"}, false},
+		// non-UTF-8 encoding
+		{"<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<rss version=\"2.0\"><channel><title>caf\xe9</title>\n  <item><link>/caf\xe9</link></item>\n</channel></rss>",
+			[]string{"http://example.com/caf%C3%A9"}, false},
+		// sitemap index: sitemaps are not returned as entries
+		{`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>http://example.com/sitemap1.xml</loc></sitemap>
+</sitemapindex>`, nil, false},
+		// RSS 2.0
+		{`<rss version="2.0"><channel><link>http://example.com/</link>
+  <item><link>http://example.com/a</link></item>
+  <item><title>no link</title></item>
+  <item><link>/b</link></item>
+</channel></rss>`, []string{"http://example.com/a", "http://example.com/b"}, false},
+		// RSS 1.0
+		{`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">
+  <channel><link>http://example.com/</link></channel>
+  <item><link>http://example.com/a</link></item>
+</rdf:RDF>`, []string{"http://example.com/a"}, false},
+		// Atom
+		{`<feed xmlns="http://www.w3.org/2005/Atom">
+  <link rel="self" href="/feed.atom"/>
+  <entry>
+    <link rel="edit" href="/edit/a"/>
+    <link rel="alternate" href="/a"/>
+  </entry>
+  <entry><link href="http://example.com/b"/></entry>
+</feed>`, []string{"http://example.com/a", "http://example.com/b"}, false},
+		// JSON Feed
+		{`{"version": "https://jsonfeed.org/version/1.1", "items": [
+  {"id": "1", "url": "http://example.com/a"},
+  {"id": "2"},
+  {"id": "3", "url": "/b"}
+]}`, []string{"http://example.com/a", "http://example.com/b"}, false},
+		// unsupported documents
+		{`<html></html>`, nil, true},
+		{``, nil, true},
+		{`{`, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := DiscoverEntriesFromReader(strings.NewReader(tt.input), "http://example.com/")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("DiscoverEntriesFromReader(%q) returned error %v, want error: %t", tt.input, err, tt.wantErr)
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("DiscoverEntriesFromReader(%q) returned %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestClient_DiscoverEntries(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+	client := New(nil)
+
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<sitemapindex>
+<sitemap><loc>/sitemap1.xml</loc></sitemap>
+<sitemap><loc>/sitemap2.xml</loc></sitemap>
+</sitemapindex>`)
+	})
+	mux.HandleFunc("/sitemap1.xml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<urlset><url><loc>/a</loc></url></urlset>`)
+	})
+	mux.HandleFunc("/sitemap2.xml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<urlset><url><loc>/b</loc></url></urlset>`)
+	})
+
+	got, err := client.DiscoverEntries(server.URL + "/sitemap.xml")
+	if err != nil {
+		t.Errorf("DiscoverEntries returned error: %v", err)
+	}
+	want := []string{server.URL + "/a", server.URL + "/b"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("DiscoverEntries returned %v, want %v", got, want)
+	}
+
+	// ensure 404 response is returned as error
+	if _, err := client.DiscoverEntries(server.URL + "/bad"); err == nil {
+		t.Errorf("DiscoverEntries(%q) did not return expected error", server.URL+"/bad")
+	}
+}
@@ -10,3 +10,5 @@ require (
 	github.com/wsxiaoys/terminal v0.0.0-20160513160801-0940f3fc43a0
 	golang.org/x/net v0.40.0
 )
+
+require golang.org/x/text v0.25.0 // indirect
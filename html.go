@@ -12,17 +12,18 @@ import (
 	"golang.org/x/net/html/atom"
 )
 
-// htmlLink parses r as HTML and returns the URL of the first link that
-// contains a webmention rel value.  HTML <link> elements are preferred,
-// falling back to <a> elements if no webmention <link> elements are found.
-func htmlLink(r io.Reader) (string, error) {
+// htmlLinks parses r as HTML and returns all <link> and <a> elements that
+// contain a webmention rel value, in document order.  Endpoint URLs are
+// returned as they appear in the document, without being resolved.
+func htmlLinks(r io.Reader) ([]EndpointCandidate, error) {
 	doc, err := html.Parse(r)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var f func(*html.Node) (string, error)
-	f = func(n *html.Node) (string, error) {
+	var links []EndpointCandidate
+	var f func(*html.Node)
+	f = func(n *html.Node) {
 		if n.Type == html.ElementNode {
 			if n.DataAtom == atom.Link || n.DataAtom == atom.A {
 				var href, rel string
@@ -38,23 +39,23 @@ func htmlLink(r io.Reader) (string, error) {
 					}
 				}
 				if hrefFound && relFound {
-					for _, v := range strings.Split(rel, " ") {
-						if v == relWebmention || v == relLegacy || v == relLegacySlash {
-							return href, nil
-						}
+					if ok, legacy := webmentionRel(strings.Split(rel, " ")); ok {
+						links = append(links, EndpointCandidate{
+							Endpoint: href,
+							Source:   EndpointSource(n.Data),
+							Legacy:   legacy,
+						})
 					}
 				}
 			}
 		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			if link, err := f(c); err == nil {
-				return link, nil
-			}
+			f(c)
 		}
-		return "", ErrNoEndpointFound
 	}
 
-	return f(doc)
+	f(doc)
+	return links, nil
 }
 
 // parseLinks parses r as HTML and returns all URLs linked to (from either a
@@ -10,43 +10,72 @@ import (
 	"github.com/google/go-cmp/cmp"
 )
 
-func TestHtmlLink(t *testing.T) {
+func TestHtmlLinks_Rel(t *testing.T) {
 	tests := []struct {
-		input, want string
-		wantErr     error
+		input string
+		want  []string
 	}{
 		// basic links
-		{`<link href="foo" rel="webmention">`, "foo", nil},
-		{`<a href="foo" rel="webmention">`, "foo", nil},
+		{`<link href="foo" rel="webmention">`, []string{"foo"}},
+		{`<a href="foo" rel="webmention">`, []string{"foo"}},
 		// different attribute order
-		{`<link rel="webmention" href="foo">`, "foo", nil},
+		{`<link rel="webmention" href="foo">`, []string{"foo"}},
 		// line breaks inside element
 		{`<link
 			rel="webmention" 
-			href="foo">`, "foo", nil},
+			href="foo">`, []string{"foo"}},
 		// multiple rel values
-		{`<link rel="a webmention b" href="foo">`, "foo", nil},
+		{`<link rel="a webmention b" href="foo">`, []string{"foo"}},
 		// legacy rel value
-		{`<link rel="http://webmention.org" href="foo">`, "foo", nil},
+		{`<link rel="http://webmention.org" href="foo">`, []string{"foo"}},
 		// legacy rel value with slash
-		{`<link rel="http://webmention.org/" href="foo">`, "foo", nil},
+		{`<link rel="http://webmention.org/" href="foo">`, []string{"foo"}},
 		// invalid legacy rel value
-		{`<link rel="https://webmention.org" href="foo">`, "", ErrNoEndpointFound},
+		{`<link rel="https://webmention.org" href="foo">`, nil},
 		// no rel value
-		{`<link href="foo">`, "", ErrNoEndpointFound},
+		{`<link href="foo">`, nil},
 		// multiple links, only one for webmention
-		{`<a href="foo" rel="web"><a href="bar" rel="webmention">`, "bar", nil},
-		// multiple webmention links, return first
-		{`<a href="foo" rel="webmention"><a href="bar" rel="webmention">`, "foo", nil},
+		{`<a href="foo" rel="web"><a href="bar" rel="webmention">`, []string{"bar"}},
+		// multiple webmention links, in document order
+		{`<a href="foo" rel="webmention"><link href="bar" rel="webmention">`, []string{"foo", "bar"}},
 	}
 
 	for _, tt := range tests {
-		buf := bytes.NewBufferString(tt.input)
-		if got, err := htmlLink(buf); err != tt.wantErr {
-			t.Errorf("htmlLink(%q) returned error: %v", tt.input, err)
-		} else if want := tt.want; got != want {
-			t.Errorf("htmlLink(%q) returned %v, want %v", tt.input, got, want)
+		links, err := htmlLinks(bytes.NewBufferString(tt.input))
+		if err != nil {
+			t.Errorf("htmlLinks(%q) returned error: %v", tt.input, err)
+			continue
 		}
+		var got []string
+		for _, l := range links {
+			got = append(got, l.Endpoint)
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("htmlLinks(%q) returned %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestHtmlLinks(t *testing.T) {
+	input := `<html><head>
+<link href="a" rel="webmention">
+<link href="b" rel="http://webmention.org/">
+</head><body>
+<a href="c" rel="other">
+<a href="d" rel="webmention http://webmention.org">
+</body></html>`
+
+	got, err := htmlLinks(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatalf("htmlLinks(%q) returned error: %v", input, err)
+	}
+	want := []EndpointCandidate{
+		{"a", SourceLink, false},
+		{"b", SourceLink, true},
+		{"d", SourceA, false},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("htmlLinks(%q) returned %v, want %v", input, got, want)
 	}
 }
 
@@ -14,16 +14,20 @@ import (
 // target URL.
 var ErrNoEndpointFound = fmt.Errorf("no endpoint found")
 
-// httpLink parses headers and returns the URL of the first link that contains
-// a webmention rel value.
-func httpLink(headers http.Header) (string, error) {
+// httpLinks parses headers and returns all links that contain a webmention
+// rel value, in order.  Endpoint URLs are returned as they appear in the
+// headers, without being resolved.
+func httpLinks(headers http.Header) []EndpointCandidate {
+	var links []EndpointCandidate
 	for _, h := range header.ParseList(headers, "Link") {
 		link := header.ParseLink(h)
-		for _, v := range link.Rel {
-			if v == relWebmention || v == relLegacy || v == relLegacySlash {
-				return link.Href, nil
-			}
+		if ok, legacy := webmentionRel(link.Rel); ok {
+			links = append(links, EndpointCandidate{
+				Endpoint: link.Href,
+				Source:   SourceHeader,
+				Legacy:   legacy,
+			})
 		}
 	}
-	return "", ErrNoEndpointFound
+	return links
 }
@@ -6,25 +6,26 @@ package webmention
 import (
 	"net/http"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
-func TestHttpLink(t *testing.T) {
+func TestHttpLinks_Rel(t *testing.T) {
 	tests := []struct {
-		input   []string
-		want    string
-		wantErr error
+		input []string
+		want  []string
 	}{
-		{[]string{`<foo>; rel="webmention"`}, "foo", nil},
-		{[]string{`<foo>; rel="a webmention b"`}, "foo", nil},
-		{[]string{`<foo>; rel="http://webmention.org"`}, "foo", nil},
-		{[]string{`<foo>; rel="http://webmention.org/"`}, "foo", nil},
-		{[]string{`<foo>; rel="https://webmention.org"`}, "", ErrNoEndpointFound},
-		{[]string{`<foo>`}, "", ErrNoEndpointFound},
-		{[]string{`<foo>; rel="a", <bar>; rel="webmention"`}, "bar", nil},
-		{[]string{`<foo>; rel="a"`, `<bar>; rel="webmention"`}, "bar", nil},
-		{[]string{`<foo>; rel="webmention", <bar>; rel="webmention"`}, "foo", nil},
-		{[]string{`<foo>; rel="webmention"`, `<bar>; rel="webmention"`}, "foo", nil},
-		{[]string{`<>; rel="webmention"`}, "", nil},
+		{[]string{`<foo>; rel="webmention"`}, []string{"foo"}},
+		{[]string{`<foo>; rel="a webmention b"`}, []string{"foo"}},
+		{[]string{`<foo>; rel="http://webmention.org"`}, []string{"foo"}},
+		{[]string{`<foo>; rel="http://webmention.org/"`}, []string{"foo"}},
+		{[]string{`<foo>; rel="https://webmention.org"`}, nil},
+		{[]string{`<foo>`}, nil},
+		{[]string{`<foo>; rel="a", <bar>; rel="webmention"`}, []string{"bar"}},
+		{[]string{`<foo>; rel="a"`, `<bar>; rel="webmention"`}, []string{"bar"}},
+		{[]string{`<foo>; rel="webmention", <bar>; rel="webmention"`}, []string{"foo", "bar"}},
+		{[]string{`<foo>; rel="webmention"`, `<bar>; rel="webmention"`}, []string{"foo", "bar"}},
+		{[]string{`<>; rel="webmention"`}, []string{""}},
 	}
 
 	for _, tt := range tests {
@@ -32,8 +33,29 @@ func TestHttpLink(t *testing.T) {
 		for _, i := range tt.input {
 			headers.Add("Link", i)
 		}
-		if got, gotErr := httpLink(headers); got != tt.want || gotErr != tt.wantErr {
-			t.Errorf("httpLink(%q) got %v (error %v), want %v (error %v)", headers, got, gotErr, tt.want, tt.wantErr)
+		var got []string
+		for _, l := range httpLinks(headers) {
+			got = append(got, l.Endpoint)
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("httpLinks(%q) returned %v, want %v", headers, got, tt.want)
 		}
 	}
 }
+
+func TestHttpLinks(t *testing.T) {
+	headers := make(http.Header)
+	headers.Add("Link", `<a>; rel="webmention", <b>; rel="other"`)
+	headers.Add("Link", `<c>; rel="http://webmention.org/"`)
+	headers.Add("Link", `<d>; rel="http://webmention.org webmention"`)
+
+	got := httpLinks(headers)
+	want := []EndpointCandidate{
+		{"a", SourceHeader, false},
+		{"c", SourceHeader, true},
+		{"d", SourceHeader, false},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("httpLinks(%q) returned %v, want %v", headers, got, want)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Operation identifies a Client operation reported to Instrumentation.
+type Operation string
+
+// Operations reported to Instrumentation.
+const (
+	OpDiscoverEndpoint Operation = "discover_endpoint"
+	OpDiscoverLinks    Operation = "discover_links"
+	OpSendWebmention   Operation = "send_webmention"
+)
+
+// Outcome describes the result of a completed operation.
+type Outcome struct {
+	// StatusCode is the HTTP status code of the last response received, or
+	// zero if no response was received.
+	StatusCode int
+
+	// Err is the error returned by the operation, if any.
+	Err error
+
+	// Duration is how long the operation took.
+	Duration time.Duration
+}
+
+// StatusClass returns the class of the outcome's status code, such as "2xx"
+// or "4xx".  If no response was received, "error" is returned.
+func (o Outcome) StatusClass() string {
+	if o.StatusCode == 0 {
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", o.StatusCode/100)
+}
+
+// Instrumentation receives timing and outcome information about the
+// operations performed by a Client, for use in tracing and metrics.
+// Implementations must be safe for concurrent use.
+type Instrumentation interface {
+	// Start is called when an operation on url begins, with the context
+	// passed to the Client method, such as DiscoverEndpointContext, or
+	// context.Background if none was.  It returns the context used for the
+	// HTTP requests made by the operation, and a function that is called
+	// with the outcome when the operation ends.
+	Start(ctx context.Context, op Operation, url string) (context.Context, func(Outcome))
+}
+
+// noopInstrumentation is the default Instrumentation, which does nothing.
+type noopInstrumentation struct{}
+
+func (noopInstrumentation) Start(ctx context.Context, _ Operation, _ string) (context.Context, func(Outcome)) {
+	return ctx, func(Outcome) {}
+}
+
+// WithInstrumentation sets the Instrumentation that receives information
+// about the operations performed by the Client.  By default, no
+// instrumentation is performed.
+func WithInstrumentation(i Instrumentation) Option {
+	return func(c *Client) {
+		c.instrumentation = i
+	}
+}
+
+// startSpan starts instrumentation of op on url, as part of the operation
+// described by ctx.  It returns the context to use for the operation's
+// requests, and a function to call with the operation's final status code and
+// error.
+func (c *Client) startSpan(ctx context.Context, op Operation, url string) (context.Context, func(status int, err error)) {
+	i := c.instrumentation
+	if i == nil {
+		i = noopInstrumentation{}
+	}
+	start := time.Now()
+	ctx, end := i.Start(ctx, op, url)
+	return ctx, func(status int, err error) {
+		end(Outcome{StatusCode: status, Err: err, Duration: time.Since(start)})
+	}
+}
+
+// HTTPTrace returns an Instrumentation that attaches the httptrace.ClientTrace
+// returned by f to the HTTP requests made by each operation.  This provides
+// access to connection level events such as DNS lookups, connection setup,
+// and time to first response byte.  If f returns nil, no trace is attached.
+func HTTPTrace(f func(op Operation, url string) *httptrace.ClientTrace) Instrumentation {
+	return httpTrace(f)
+}
+
+type httpTrace func(op Operation, url string) *httptrace.ClientTrace
+
+func (f httpTrace) Start(ctx context.Context, op Operation, url string) (context.Context, func(Outcome)) {
+	if trace := f(op, url); trace != nil {
+		ctx = httptrace.WithClientTrace(ctx, trace)
+	}
+	return ctx, func(Outcome) {}
+}
+
+// Metrics is an Instrumentation that counts completed operations by status
+// class and whether they succeeded, and records their total duration.  It is
+// useful for exposing basic metrics without an external collector.  The zero
+// value is ready to use.
+type Metrics struct {
+	mu        sync.Mutex
+	counts    map[metricsKey]int
+	durations map[Operation]time.Duration
+}
+
+type metricsKey struct {
+	op    Operation
+	class string
+	ok    bool
+}
+
+// Start implements Instrumentation.
+func (m *Metrics) Start(ctx context.Context, op Operation, _ string) (context.Context, func(Outcome)) {
+	return ctx, func(o Outcome) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.counts == nil {
+			m.counts = make(map[metricsKey]int)
+			m.durations = make(map[Operation]time.Duration)
+		}
+		m.counts[metricsKey{op, o.StatusClass(), o.Err == nil}]++
+		m.durations[op] += o.Duration
+	}
+}
+
+// Count returns the number of completed operations op with the given status
+// class, as returned by Outcome.StatusClass, that succeeded (if ok is true)
+// or failed.  An operation fails if it returns an error, even if it received
+// a successful response, such as a discovery that finds no endpoint.
+func (m *Metrics) Count(op Operation, class string, ok bool) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[metricsKey{op, class, ok}]
+}
+
+// Duration returns the total duration of all completed operations op.
+func (m *Metrics) Duration(op Operation) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.durations[op]
+}
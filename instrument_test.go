@@ -0,0 +1,196 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOutcome_StatusClass(t *testing.T) {
+	tests := []struct {
+		outcome Outcome
+		want    string
+	}{
+		{Outcome{StatusCode: 200}, "2xx"},
+		{Outcome{StatusCode: 202}, "2xx"},
+		{Outcome{StatusCode: 404, Err: errors.New("response error: 404")}, "4xx"},
+		{Outcome{StatusCode: 503}, "5xx"},
+		{Outcome{Err: errors.New("network error")}, "error"},
+	}
+	for _, tt := range tests {
+		if got := tt.outcome.StatusClass(); got != tt.want {
+			t.Errorf("%v.StatusClass() returned %q, want %q", tt.outcome, got, tt.want)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	mux.HandleFunc("/good", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</endpoint>; rel=webmention")
+	})
+	mux.HandleFunc("/none", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/endpoint", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	m := new(Metrics)
+	client := NewWithOptions(WithInstrumentation(m))
+
+	if _, err := client.DiscoverEndpoint(server.URL + "/good"); err != nil {
+		t.Errorf("DiscoverEndpoint returned error: %v", err)
+	}
+	if _, err := client.DiscoverEndpoint(server.URL + "/bad"); err == nil {
+		t.Errorf("DiscoverEndpoint did not return expected error")
+	}
+	if _, err := client.DiscoverEndpoint(server.URL + "/none"); err != ErrNoEndpointFound {
+		t.Errorf("DiscoverEndpoint returned error %v, want %v", err, ErrNoEndpointFound)
+	}
+	if _, err := client.DiscoverLinks(server.URL+"/good", ""); err != nil {
+		t.Errorf("DiscoverLinks returned error: %v", err)
+	}
+	for _, path := range []string{"/endpoint", "/endpoint", "/bad"} {
+		if resp, _ := client.SendWebmention(server.URL+path, "S", "T"); resp != nil {
+			_ = resp.Body.Close()
+		}
+	}
+	closed := httptest.NewServer(nil)
+	closed.Close()
+	if resp, err := client.SendWebmention(closed.URL, "S", "T"); err == nil {
+		_ = resp.Body.Close()
+		t.Errorf("SendWebmention did not return expected error")
+	}
+
+	tests := []struct {
+		op    Operation
+		class string
+		ok    bool
+		want  int
+	}{
+		{OpDiscoverEndpoint, "2xx", true, 1},
+		{OpDiscoverEndpoint, "2xx", false, 1}, // no endpoint found
+		{OpDiscoverEndpoint, "4xx", true, 0},
+		{OpDiscoverEndpoint, "4xx", false, 1},
+		{OpDiscoverLinks, "2xx", true, 1},
+		{OpSendWebmention, "2xx", true, 2},
+		{OpSendWebmention, "4xx", false, 1},
+		{OpSendWebmention, "error", false, 1},
+	}
+	for _, tt := range tests {
+		if got := m.Count(tt.op, tt.class, tt.ok); got != tt.want {
+			t.Errorf("Count(%q, %q, %t) returned %d, want %d", tt.op, tt.class, tt.ok, got, tt.want)
+		}
+	}
+	if m.Duration(OpSendWebmention) <= 0 {
+		t.Errorf("Duration(%q) returned %v, want positive duration", OpSendWebmention, m.Duration(OpSendWebmention))
+	}
+}
+
+type ctxKey struct{}
+
+// ctxInstrumentation records the value of ctxKey in the contexts passed to
+// Start.
+type ctxInstrumentation struct {
+	mu     sync.Mutex
+	values []any
+}
+
+func (i *ctxInstrumentation) Start(ctx context.Context, _ Operation, _ string) (context.Context, func(Outcome)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.values = append(i.values, ctx.Value(ctxKey{}))
+	return ctx, func(Outcome) {}
+}
+
+func TestClient_Context(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</endpoint>; rel=webmention")
+	})
+
+	inst := new(ctxInstrumentation)
+	client := NewWithOptions(WithInstrumentation(inst))
+	ctx := context.WithValue(context.Background(), ctxKey{}, "parent")
+
+	if _, err := client.DiscoverEndpointContext(ctx, server.URL+"/"); err != nil {
+		t.Errorf("DiscoverEndpointContext returned error: %v", err)
+	}
+	if _, err := client.DiscoverEndpointDetailedContext(ctx, server.URL+"/"); err != nil {
+		t.Errorf("DiscoverEndpointDetailedContext returned error: %v", err)
+	}
+	if _, err := client.DiscoverLinksContext(ctx, server.URL+"/", ""); err != nil {
+		t.Errorf("DiscoverLinksContext returned error: %v", err)
+	}
+	if resp, err := client.SendWebmentionContext(ctx, server.URL+"/endpoint", "S", "T"); err != nil {
+		t.Errorf("SendWebmentionContext returned error: %v", err)
+	} else {
+		_ = resp.Body.Close()
+	}
+	if _, err := client.DiscoverEndpoint(server.URL + "/"); err != nil {
+		t.Errorf("DiscoverEndpoint returned error: %v", err)
+	}
+
+	want := []any{"parent", "parent", "parent", "parent", nil}
+	if !cmp.Equal(inst.values, want) {
+		t.Errorf("Instrumentation received context values %v, want %v", inst.values, want)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.DiscoverEndpointContext(canceled, server.URL+"/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("DiscoverEndpointContext with canceled context returned error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestHTTPTrace(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	var mu sync.Mutex
+	var got []Operation
+	client := NewWithOptions(WithInstrumentation(HTTPTrace(func(op Operation, url string) *httptrace.ClientTrace {
+		return &httptrace.ClientTrace{
+			GotFirstResponseByte: func() {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, op)
+			},
+		}
+	})))
+
+	if _, err := client.DiscoverLinks(server.URL, ""); err != nil {
+		t.Errorf("DiscoverLinks returned error: %v", err)
+	}
+	if _, err := client.DiscoverEndpoint(server.URL); err != ErrNoEndpointFound {
+		t.Errorf("DiscoverEndpoint returned error %v, want %v", err, ErrNoEndpointFound)
+	}
+
+	// one request for links, HEAD and GET for endpoint discovery
+	want := []Operation{OpDiscoverLinks, OpDiscoverEndpoint, OpDiscoverEndpoint}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != len(want) {
+		t.Fatalf("traced requests for %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("traced requests for %v, want %v", got, want)
+			break
+		}
+	}
+}
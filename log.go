@@ -0,0 +1,35 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"context"
+	"log/slog"
+)
+
+// log returns the configured logger, or a logger that discards all records
+// if none is configured.
+func (c *Client) log() *slog.Logger {
+	if c.logger == nil {
+		return discardLogger
+	}
+	return c.logger
+}
+
+// logRedirects logs each redirect in chain.
+func logRedirects(log *slog.Logger, chain []Redirect) {
+	for _, r := range chain {
+		log.Debug("followed redirect", "from", r.From, "to", r.To, "status", r.StatusCode)
+	}
+}
+
+var discardLogger = slog.New(discardHandler{})
+
+// discardHandler is a slog.Handler that discards all records.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
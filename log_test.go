@@ -0,0 +1,80 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClient_Logging(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<a href="/endpoint" rel="webmention">`)
+	})
+	mux.HandleFunc("/endpoint", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := NewWithOptions(WithLogger(logger))
+
+	endpoint, err := client.DiscoverEndpoint(server.URL + "/redirect")
+	if err != nil {
+		t.Fatalf("DiscoverEndpoint returned error: %v", err)
+	}
+	resp, err := client.SendWebmention(endpoint, "S", "T")
+	if err != nil {
+		t.Fatalf("SendWebmention returned error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	var got []string
+	records := map[string]map[string]any{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r map[string]any
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("error decoding log record: %v", err)
+		}
+		msg := r["msg"].(string)
+		if m, ok := r["method"].(string); ok {
+			got = append(got, m+" "+msg)
+		}
+		records[msg] = r
+	}
+
+	want := []string{
+		// HEAD
+		"HEAD followed redirect",
+		"HEAD webmention discovery request",
+		"HEAD no webmention endpoint found",
+		// GET
+		"GET followed redirect",
+		"GET webmention discovery request",
+		"GET webmention endpoint found",
+	}
+	if len(got) < len(want) || !cmp.Equal(got[:len(want)], want) {
+		t.Errorf("logged messages %q, want %q", got, want)
+	}
+
+	if got, want := records["webmention endpoint found"]["from"], "a"; got != want {
+		t.Errorf("endpoint found from %v, want %v", got, want)
+	}
+	if got, want := records["webmention sent"]["status"], float64(http.StatusAccepted); got != want {
+		t.Errorf("webmention sent with status %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// A TargetMatcher reports whether a webmention target URL is hosted by a site,
+// and so whether webmentions for it should be accepted.
+type TargetMatcher interface {
+	MatchTarget(ctx context.Context, target string) (bool, error)
+}
+
+// Normalization is a set of rules applied to URLs before they are compared.
+type Normalization int
+
+const (
+	// IgnoreScheme treats http and https URLs as equivalent.
+	IgnoreScheme Normalization = 1 << iota
+
+	// IgnoreWWW treats a "www." host prefix as insignificant, so
+	// www.example.com and example.com are equivalent.
+	IgnoreWWW
+
+	// IgnoreTrailingSlash treats paths with and without a trailing slash
+	// as equivalent.
+	IgnoreTrailingSlash
+
+	// IgnoreFragment removes URL fragments.
+	IgnoreFragment
+
+	// DefaultNormalization applies all normalization rules.
+	DefaultNormalization = IgnoreScheme | IgnoreWWW | IgnoreTrailingSlash | IgnoreFragment
+)
+
+// A MatcherOption configures the TargetMatcher returned by NewTargetMatcher.
+type MatcherOption func(*urlMatcher)
+
+// MatchPathPrefixes limits matched targets to those whose path begins with
+// one of the provided prefixes.  If not specified, all paths are matched.
+func MatchPathPrefixes(prefixes ...string) MatcherOption {
+	return func(m *urlMatcher) {
+		m.prefixes = append(m.prefixes, prefixes...)
+	}
+}
+
+// MatchNormalization sets the rules used to normalize URLs before they are
+// compared.  If not specified, DefaultNormalization is used.
+func MatchNormalization(n Normalization) MatcherOption {
+	return func(m *urlMatcher) {
+		m.norm = n
+	}
+}
+
+// MatchExists sets a function that reports whether a target exists on the
+// site, such as by looking it up in a database of published pages.  It is
+// called only for targets that otherwise match, with the target URL
+// normalized according to the configured rules, so that equivalent targets
+// are passed as the same URL.  With IgnoreScheme, the URL has the scheme of
+// the matched host, or https if the host was configured without one.  An
+// error returned by exists is returned from MatchTarget.
+func MatchExists(exists func(ctx context.Context, target *url.URL) (bool, error)) MatcherOption {
+	return func(m *urlMatcher) {
+		m.exists = exists
+	}
+}
+
+// NewTargetMatcher returns a TargetMatcher that matches http and https URLs
+// on the provided hosts.  Each host may be a bare hostname such as
+// "example.com", or a URL such as "https://example.com" to also require a
+// scheme when IgnoreScheme is not in effect.  Hosts are compared case
+// insensitively, ignoring default ports.
+func NewTargetMatcher(hosts []string, opts ...MatcherOption) TargetMatcher {
+	m := &urlMatcher{norm: DefaultNormalization}
+	for _, opt := range opts {
+		opt(m)
+	}
+	for _, h := range hosts {
+		var scheme string
+		if u, err := url.Parse(h); err == nil && u.Host != "" {
+			scheme, h = strings.ToLower(u.Scheme), u.Host
+		}
+		m.hosts = append(m.hosts, matchHost{scheme: scheme, host: m.normalizeHost(scheme, h)})
+	}
+	return m
+}
+
+type urlMatcher struct {
+	hosts    []matchHost
+	prefixes []string
+	norm     Normalization
+	exists   func(context.Context, *url.URL) (bool, error)
+}
+
+type matchHost struct {
+	scheme string // empty matches both http and https
+	host   string // normalized host
+}
+
+func (m *urlMatcher) MatchTarget(ctx context.Context, target string) (bool, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false, err
+	}
+	u = m.normalize(u)
+	if u == nil || !m.matchPath(u.Path) {
+		return false, nil
+	}
+	h, ok := m.matchHost(u)
+	if !ok {
+		return false, nil
+	}
+	if m.exists == nil {
+		return true, nil
+	}
+	if m.norm&IgnoreScheme != 0 {
+		// use one scheme, so equivalent targets are looked up the same
+		u.Scheme = h.scheme
+		if u.Scheme == "" {
+			u.Scheme = "https"
+		}
+	}
+	return m.exists(ctx, u)
+}
+
+// normalize returns a normalized copy of u, or nil if u is not an absolute
+// http or https URL.
+func (m *urlMatcher) normalize(u *url.URL) *url.URL {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	if n.Scheme != "http" && n.Scheme != "https" || n.Host == "" {
+		return nil
+	}
+	n.Host = m.normalizeHost(n.Scheme, n.Host)
+	if n.Path == "" {
+		n.Path, n.RawPath = "/", ""
+	}
+	if m.norm&IgnoreTrailingSlash != 0 && len(n.Path) > 1 {
+		n.Path = strings.TrimSuffix(n.Path, "/")
+		n.RawPath = strings.TrimSuffix(n.RawPath, "/")
+	}
+	if m.norm&IgnoreFragment != 0 {
+		n.Fragment, n.RawFragment = "", ""
+	}
+	return &n
+}
+
+// normalizeHost lowercases host and removes the default port for scheme, and
+// the "www." prefix if IgnoreWWW is set.
+func (m *urlMatcher) normalizeHost(scheme, host string) string {
+	host = strings.ToLower(host)
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if (port == "80" && scheme != "https") || (port == "443" && scheme != "http") {
+			host = h
+		}
+	}
+	if m.norm&IgnoreWWW != 0 {
+		host = strings.TrimPrefix(host, "www.")
+	}
+	return host
+}
+
+// matchHost returns the configured host that u matches, if any.
+func (m *urlMatcher) matchHost(u *url.URL) (matchHost, bool) {
+	for _, h := range m.hosts {
+		if h.host != u.Host {
+			continue
+		}
+		if h.scheme == "" || m.norm&IgnoreScheme != 0 || h.scheme == u.Scheme {
+			return h, true
+		}
+	}
+	return matchHost{}, false
+}
+
+// matchPath reports whether the normalized path matches one of the configured
+// prefixes.
+func (m *urlMatcher) matchPath(path string) bool {
+	if len(m.prefixes) == 0 {
+		return true
+	}
+	if m.norm&IgnoreTrailingSlash != 0 && !strings.HasSuffix(path, "/") {
+		// allow "/blog" to match the prefix "/blog/"
+		path += "/"
+	}
+	for _, p := range m.prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
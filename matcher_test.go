@@ -0,0 +1,107 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestTargetMatcher(t *testing.T) {
+	tests := []struct {
+		description string
+		hosts       []string
+		opts        []MatcherOption
+		target      string
+		want        bool
+		wantErr     bool
+	}{
+		{"exact", []string{"example.com"}, nil, "https://example.com/post", true, false},
+		{"other host", []string{"example.com"}, nil, "https://example.org/post", false, false},
+		{"subdomain", []string{"example.com"}, nil, "https://blog.example.com/post", false, false},
+		{"host case", []string{"Example.com"}, nil, "https://EXAMPLE.com/post", true, false},
+		{"default port", []string{"example.com"}, nil, "https://example.com:443/post", true, false},
+		{"other port", []string{"example.com"}, nil, "https://example.com:8443/post", false, false},
+		{"http", []string{"https://example.com"}, nil, "http://example.com/post", true, false},
+		{"www", []string{"example.com"}, nil, "https://www.example.com/post", true, false},
+		{"www configured", []string{"www.example.com"}, nil, "https://example.com/post", true, false},
+		{"not http", []string{"example.com"}, nil, "ftp://example.com/post", false, false},
+		{"relative", []string{"example.com"}, nil, "/post", false, false},
+		{"invalid", []string{"example.com"}, nil, "https://example.com/%zz", false, true},
+
+		// path prefixes
+		{"prefix", []string{"example.com"}, []MatcherOption{MatchPathPrefixes("/blog/")}, "https://example.com/blog/post", true, false},
+		{"prefix trailing slash", []string{"example.com"}, []MatcherOption{MatchPathPrefixes("/blog/")}, "https://example.com/blog", true, false},
+		{"prefix not matched", []string{"example.com"}, []MatcherOption{MatchPathPrefixes("/blog/")}, "https://example.com/about", false, false},
+		{"prefix root", []string{"example.com"}, []MatcherOption{MatchPathPrefixes("/")}, "https://example.com", true, false},
+
+		// strict normalization
+		{"strict http", []string{"https://example.com"}, []MatcherOption{MatchNormalization(0)}, "http://example.com/post", false, false},
+		{"strict https", []string{"https://example.com"}, []MatcherOption{MatchNormalization(0)}, "https://example.com/post", true, false},
+		{"strict bare host", []string{"example.com"}, []MatcherOption{MatchNormalization(0)}, "http://example.com/post", true, false},
+		{"strict www", []string{"example.com"}, []MatcherOption{MatchNormalization(0)}, "https://www.example.com/post", false, false},
+		{"strict prefix", []string{"example.com"}, []MatcherOption{MatchNormalization(0), MatchPathPrefixes("/blog/")}, "https://example.com/blog", false, false},
+	}
+
+	for _, tt := range tests {
+		m := NewTargetMatcher(tt.hosts, tt.opts...)
+		got, err := m.MatchTarget(context.Background(), tt.target)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: MatchTarget(%q) returned error %v, want error: %t", tt.description, tt.target, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("%v: MatchTarget(%q) returned %t, want %t", tt.description, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestMatchExists(t *testing.T) {
+	errLookup := errors.New("lookup failed")
+	pages := map[string]bool{
+		"https://example.com/":       true,
+		"https://example.com/post":   true,
+		"http://legacy.example/post": true,
+	}
+	var looked []string
+	exists := func(_ context.Context, u *url.URL) (bool, error) {
+		looked = append(looked, u.String())
+		if u.Path == "/error" {
+			return false, errLookup
+		}
+		return pages[u.String()], nil
+	}
+
+	tests := []struct {
+		target     string
+		want       bool
+		wantErr    error
+		wantLooked string
+	}{
+		{"https://example.com", true, nil, "https://example.com/"},
+		{"http://www.example.com/post/#comments", true, nil, "https://example.com/post"},
+		{"https://legacy.example/post", true, nil, "http://legacy.example/post"},
+		{"https://example.com/missing", false, nil, "https://example.com/missing"},
+		{"https://example.com/error", false, errLookup, "https://example.com/error"},
+		{"https://example.org/post", false, nil, ""},
+	}
+
+	m := NewTargetMatcher([]string{"example.com", "http://legacy.example"}, MatchExists(exists))
+	for _, tt := range tests {
+		looked = nil
+		got, err := m.MatchTarget(context.Background(), tt.target)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("MatchTarget(%q) returned error %v, want %v", tt.target, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("MatchTarget(%q) returned %t, want %t", tt.target, got, tt.want)
+		}
+		if tt.wantLooked == "" && len(looked) > 0 {
+			t.Errorf("MatchTarget(%q) looked up %v, want no lookup", tt.target, looked)
+		} else if tt.wantLooked != "" && (len(looked) != 1 || looked[0] != tt.wantLooked) {
+			t.Errorf("MatchTarget(%q) looked up %v, want %q", tt.target, looked, tt.wantLooked)
+		}
+	}
+}
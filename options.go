@@ -0,0 +1,101 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithUserAgent sets the User-Agent header sent with all requests made by the
+// Client, including discovery, link fetching, and sending webmentions.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithHeader adds a header sent with all requests made by the Client.  It may
+// be specified multiple times to add multiple values for the same key.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+		c.header.Add(key, value)
+	}
+}
+
+// WithAccept sets the Accept header sent with all requests made by the
+// Client, such as "text/html, application/xhtml+xml;q=0.9, */*;q=0.8".
+func WithAccept(accept string) Option {
+	return func(c *Client) {
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+		c.header.Set("Accept", accept)
+	}
+}
+
+// WithHTTPClient sets the http.Client used to make requests.  If not
+// specified, http.DefaultClient is used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.Client = client
+	}
+}
+
+// WithTimeout sets the time limit for each request made by the Client,
+// overriding any timeout on the configured http.Client.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry failed requests.  By default,
+// requests are not retried.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// WithCache sets a cache used to store discovered webmention endpoints.  By
+// default, endpoints are not cached.
+func WithCache(cache Cache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// WithLogger sets the logger used by the Client.  By default, nothing is
+// logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = l
+	}
+}
+
+// WithMaxBodySize limits the number of bytes read from response bodies when
+// discovering endpoints, links, and feed entries.  A value of zero or less
+// means no limit, which is the default.
+func WithMaxBodySize(n int64) Option {
+	return func(c *Client) {
+		c.maxBodySize = n
+	}
+}
+
+// WithRedirectPolicy sets the policy for following redirects, overriding the
+// CheckRedirect function of the configured http.Client.  See
+// http.Client.CheckRedirect for details.
+func WithRedirectPolicy(f func(req *http.Request, via []*http.Request) error) Option {
+	return func(c *Client) {
+		c.checkRedirect = f
+	}
+}
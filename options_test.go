@@ -0,0 +1,77 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	hc := &http.Client{}
+	c := NewWithOptions(WithHTTPClient(hc))
+	if c.Client != hc {
+		t.Errorf("NewWithOptions did not use provided http.Client")
+	}
+
+	c = NewWithOptions()
+	if c.Client != http.DefaultClient {
+		t.Errorf("NewWithOptions did not use http.DefaultClient by default")
+	}
+
+	// client-level settings must not modify the provided client
+	c = NewWithOptions(WithTimeout(time.Second))
+	if c.Client == http.DefaultClient {
+		t.Errorf("WithTimeout modified http.DefaultClient")
+	}
+	if got, want := c.Timeout, time.Second; got != want {
+		t.Errorf("WithTimeout set timeout %v, want %v", got, want)
+	}
+	if http.DefaultClient.Timeout != 0 {
+		t.Errorf("WithTimeout modified http.DefaultClient")
+	}
+}
+
+func TestWithMaxBodySize(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, strings.Repeat(" ", 100)+`<link href="/endpoint" rel="webmention">`)
+	})
+
+	client := NewWithOptions(WithMaxBodySize(50))
+	if _, err := client.DiscoverEndpoint(server.URL); err != ErrNoEndpointFound {
+		t.Errorf("DiscoverEndpoint returned error %v, want %v", err, ErrNoEndpointFound)
+	}
+
+	client = NewWithOptions(WithMaxBodySize(1000))
+	if _, err := client.DiscoverEndpoint(server.URL); err != nil {
+		t.Errorf("DiscoverEndpoint returned error: %v", err)
+	}
+}
+
+func TestWithRedirectPolicy(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</endpoint>; rel=webmention")
+	})
+
+	errNoRedirect := errors.New("no redirects")
+	client := NewWithOptions(WithRedirectPolicy(func(*http.Request, []*http.Request) error {
+		return errNoRedirect
+	}))
+	if _, err := client.DiscoverEndpoint(server.URL + "/redirect"); !errors.Is(err, errNoRedirect) {
+		t.Errorf("DiscoverEndpoint returned error %v, want %v", err, errNoRedirect)
+	}
+}
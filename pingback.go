@@ -0,0 +1,333 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const relPingback = "pingback"
+
+// Errors corresponding to the fault codes defined by the Pingback
+// specification.  A PingbackFault with one of these codes wraps the
+// corresponding error, so callers can use errors.Is to check for them.
+var (
+	ErrPingbackSourceNotFound   = errors.New("pingback: source URI does not exist")
+	ErrPingbackNoLink           = errors.New("pingback: source URI does not contain a link to the target URI")
+	ErrPingbackTargetNotFound   = errors.New("pingback: target URI does not exist")
+	ErrPingbackTargetInvalid    = errors.New("pingback: target URI cannot be used as a target")
+	ErrPingbackAlreadyExists    = errors.New("pingback: pingback has already been registered")
+	ErrPingbackAccessDenied     = errors.New("pingback: access denied")
+	ErrPingbackUpstreamError    = errors.New("pingback: could not communicate with upstream server")
+	errPingbackFaultUnspecified = errors.New("pingback: fault")
+)
+
+// pingbackFaults maps Pingback fault codes to their errors.
+var pingbackFaults = map[int]error{
+	0x10: ErrPingbackSourceNotFound,
+	0x11: ErrPingbackNoLink,
+	0x20: ErrPingbackTargetNotFound,
+	0x21: ErrPingbackTargetInvalid,
+	0x30: ErrPingbackAlreadyExists,
+	0x31: ErrPingbackAccessDenied,
+	0x32: ErrPingbackUpstreamError,
+}
+
+// PingbackFault is an XML-RPC fault returned by a Pingback server.
+type PingbackFault struct {
+	Code    int
+	Message string
+}
+
+func (f *PingbackFault) Error() string {
+	return fmt.Sprintf("pingback fault %d: %s", f.Code, f.Message)
+}
+
+// Unwrap returns the error corresponding to the fault code, such as
+// ErrPingbackNoLink.
+func (f *PingbackFault) Unwrap() error {
+	if err, ok := pingbackFaults[f.Code]; ok {
+		return err
+	}
+	return errPingbackFaultUnspecified
+}
+
+// WithPingbackFallback enables sending a Pingback from Send when a target
+// does not advertise a webmention endpoint.
+func WithPingbackFallback(enabled bool) Option {
+	return func(c *Client) {
+		c.pingbackFallback = enabled
+	}
+}
+
+// DiscoverPingback discovers the Pingback server for the provided URL, using
+// the X-Pingback HTTP header or an HTML <link rel="pingback"> element.  If no
+// server is found, ErrNoEndpointFound is returned.
+func (c *Client) DiscoverPingback(urlStr string) (string, error) {
+	ctx := context.Background()
+	req, err := c.newRequest(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body = c.limitBody(resp.Body)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if code := resp.StatusCode; code < 200 || 300 <= code {
+		return "", fmt.Errorf("response error: %v", resp.StatusCode)
+	}
+
+	server := strings.TrimSpace(resp.Header.Get("X-Pingback"))
+	if server == "" {
+		server, err = htmlPingbackLink(resp.Body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	urls, err := resolveReferences(resp.Request.URL.String(), server)
+	if err != nil {
+		return "", err
+	}
+	if len(urls) == 0 {
+		return "", ErrNoEndpointFound
+	}
+	c.log().Debug("pingback server found", "url", urlStr, "server", urls[0])
+	return urls[0], nil
+}
+
+// htmlPingbackLink parses r as HTML and returns the URL of the first <link>
+// element with a pingback rel value.
+func htmlPingbackLink(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+
+	var f func(*html.Node) (string, bool)
+	f = func(n *html.Node) (string, bool) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Link {
+			var href, rel string
+			var hrefFound bool
+			for _, a := range n.Attr {
+				switch a.Key {
+				case atom.Href.String():
+					href, hrefFound = a.Val, true
+				case atom.Rel.String():
+					rel = a.Val
+				}
+			}
+			if hrefFound {
+				for _, v := range strings.Fields(rel) {
+					if v == relPingback {
+						return href, true
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if href, ok := f(c); ok {
+				return href, true
+			}
+		}
+		return "", false
+	}
+
+	if href, ok := f(doc); ok {
+		return href, nil
+	}
+	return "", ErrNoEndpointFound
+}
+
+// SendPingback sends a Pingback to server, indicating that source has
+// mentioned target.  If the server returns an XML-RPC fault, the returned
+// error is a *PingbackFault.
+func (c *Client) SendPingback(server, source, target string) error {
+	body, err := xml.Marshal(xmlrpcCall{
+		MethodName: "pingback.ping",
+		Params:     []xmlrpcParam{{xmlrpcValue{String: source}}, {xmlrpcValue{String: target}}},
+	})
+	if err != nil {
+		return err
+	}
+	body = append([]byte(xml.Header), body...)
+
+	log := c.log().With("server", server, "source", source, "target", target)
+	req, err := c.newRequest(context.Background(), http.MethodPost, server, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+
+	resp, err := c.do(req)
+	if err != nil {
+		log.Error("pingback send failed", "error", err)
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if code := resp.StatusCode; code < 200 || 300 <= code {
+		err := fmt.Errorf("response error: %v", resp.StatusCode)
+		log.Error("pingback send failed", "status", resp.StatusCode, "error", err)
+		return err
+	}
+
+	var r xmlrpcResponse
+	if err := xml.NewDecoder(c.limitBody(resp.Body)).Decode(&r); err != nil {
+		return fmt.Errorf("pingback: invalid response: %w", err)
+	}
+	if r.Fault != nil {
+		fault := r.Fault.Value.fault()
+		log.Error("pingback send failed", "error", fault)
+		return fault
+	}
+	log.Info("pingback sent")
+	return nil
+}
+
+// SendResult describes a mention sent by Send.
+type SendResult struct {
+	// Endpoint is the webmention endpoint or Pingback server the mention
+	// was sent to.
+	Endpoint string
+
+	// Pingback is true if the mention was sent as a Pingback.
+	Pingback bool
+
+	// StatusCode is the HTTP status code returned by the webmention
+	// endpoint.  It is zero for Pingbacks.
+	StatusCode int
+
+	// Location is the Location header returned by the webmention endpoint,
+	// if any.
+	Location string
+}
+
+// Send discovers the webmention endpoint for target and sends a webmention
+// indicating that source has mentioned target.  If target does not advertise
+// a webmention endpoint and Pingback fallback is enabled with
+// WithPingbackFallback, a Pingback is sent instead.
+func (c *Client) Send(source, target string) (*SendResult, error) {
+	endpoint, err := c.DiscoverEndpoint(target)
+	if errors.Is(err, ErrNoEndpointFound) && c.pingbackFallback {
+		server, perr := c.DiscoverPingback(target)
+		if errors.Is(perr, ErrNoEndpointFound) {
+			return nil, err
+		} else if perr != nil {
+			return nil, fmt.Errorf("pingback discovery: %w", perr)
+		}
+		if err := c.SendPingback(server, source, target); err != nil {
+			return nil, err
+		}
+		return &SendResult{Endpoint: server, Pingback: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.SendWebmention(endpoint, source, target)
+	if resp != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SendResult{
+		Endpoint:   endpoint,
+		StatusCode: resp.StatusCode,
+		Location:   resp.Header.Get("Location"),
+	}, nil
+}
+
+// xmlrpcCall is an XML-RPC method call.
+type xmlrpcCall struct {
+	XMLName    xml.Name      `xml:"methodCall"`
+	MethodName string        `xml:"methodName"`
+	Params     []xmlrpcParam `xml:"params>param"`
+}
+
+// xmlrpcResponse is an XML-RPC method response.
+type xmlrpcResponse struct {
+	XMLName xml.Name      `xml:"methodResponse"`
+	Params  []xmlrpcParam `xml:"params>param,omitempty"`
+	Fault   *xmlrpcFault  `xml:"fault,omitempty"`
+}
+
+type xmlrpcParam struct {
+	Value xmlrpcValue `xml:"value"`
+}
+
+type xmlrpcFault struct {
+	Value xmlrpcValue `xml:"value"`
+}
+
+// xmlrpcValue is an XML-RPC value.  Only the types used by Pingback are
+// supported: strings, integers, and structs.
+type xmlrpcValue struct {
+	String string        `xml:"string,omitempty"`
+	Int    string        `xml:"int,omitempty"`
+	I4     string        `xml:"i4,omitempty"`
+	Struct *xmlrpcStruct `xml:"struct,omitempty"`
+	Text   string        `xml:",chardata"` // untyped values are strings
+}
+
+type xmlrpcStruct struct {
+	Members []xmlrpcMember `xml:"member"`
+}
+
+type xmlrpcMember struct {
+	Name  string      `xml:"name"`
+	Value xmlrpcValue `xml:"value"`
+}
+
+// str returns v as a string.
+func (v xmlrpcValue) str() string {
+	if v.String != "" {
+		return v.String
+	}
+	return strings.TrimSpace(v.Text)
+}
+
+// int returns v as an integer, or zero if it is not one.
+func (v xmlrpcValue) int() int {
+	s := v.Int
+	if s == "" {
+		s = v.I4
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
+
+// fault returns the fault described by the struct v.
+func (v xmlrpcValue) fault() *PingbackFault {
+	f := new(PingbackFault)
+	if v.Struct == nil {
+		return f
+	}
+	for _, m := range v.Struct.Members {
+		switch m.Name {
+		case "faultCode":
+			f.Code = m.Value.int()
+		case "faultString":
+			f.Message = m.Value.str()
+		}
+	}
+	return f
+}
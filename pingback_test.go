@@ -0,0 +1,185 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClient_DiscoverPingback(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+	client := New(nil)
+
+	mux.HandleFunc("/header", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Pingback", "/xmlrpc-header")
+		_, _ = fmt.Fprint(w, `<link rel="pingback" href="/xmlrpc-link">`)
+	})
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<link rel="stylesheet" href="/style.css"><link rel="pingback" href="/xmlrpc-link">`)
+	})
+	mux.HandleFunc("/none", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<a rel="pingback" href="/xmlrpc">`)
+	})
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{"/header", server.URL + "/xmlrpc-header", nil},
+		{"/link", server.URL + "/xmlrpc-link", nil},
+		{"/none", "", ErrNoEndpointFound},
+	}
+	for _, tt := range tests {
+		got, err := client.DiscoverPingback(server.URL + tt.path)
+		if got != tt.want || err != tt.wantErr {
+			t.Errorf("DiscoverPingback(%q) returned %v (error %v), want %v (error %v)", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	// ensure 404 response is returned as error
+	if _, err := client.DiscoverPingback(server.URL + "/bad"); err == nil {
+		t.Errorf("DiscoverPingback(%q) did not return expected error", server.URL+"/bad")
+	}
+}
+
+// pingbackHandler returns an XML-RPC handler that records pingback.ping
+// calls and responds with the given fault code, or success if zero.
+func pingbackHandler(t *testing.T, calls *[][2]string, faultCode int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var call xmlrpcCall
+		if err := xml.NewDecoder(r.Body).Decode(&call); err != nil {
+			t.Errorf("error decoding XML-RPC call: %v", err)
+		}
+		if call.MethodName != "pingback.ping" || len(call.Params) != 2 {
+			t.Errorf("unexpected XML-RPC call %+v", call)
+			return
+		}
+		*calls = append(*calls, [2]string{call.Params[0].Value.str(), call.Params[1].Value.str()})
+
+		w.Header().Set("Content-Type", "text/xml")
+		if faultCode != 0 {
+			_, _ = fmt.Fprintf(w, `<?xml version="1.0"?>
+<methodResponse><fault><value><struct>
+  <member><name>faultCode</name><value><int>%d</int></value></member>
+  <member><name>faultString</name><value><string>fault message</string></value></member>
+</struct></value></fault></methodResponse>`, faultCode)
+			return
+		}
+		_, _ = fmt.Fprint(w, `<?xml version="1.0"?>
+<methodResponse><params><param><value>Thanks!</value></param></params></methodResponse>`)
+	}
+}
+
+func TestClient_SendPingback(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+	client := New(nil)
+
+	var calls [][2]string
+	mux.HandleFunc("/ok", pingbackHandler(t, &calls, 0))
+	mux.HandleFunc("/nolink", pingbackHandler(t, &calls, 0x11))
+	mux.HandleFunc("/other", pingbackHandler(t, &calls, 99))
+
+	if err := client.SendPingback(server.URL+"/ok", "S", "T"); err != nil {
+		t.Errorf("SendPingback returned error: %v", err)
+	}
+	if want := [][2]string{{"S", "T"}}; !cmp.Equal(calls, want) {
+		t.Errorf("server received calls %v, want %v", calls, want)
+	}
+
+	err := client.SendPingback(server.URL+"/nolink", "S", "T")
+	if !errors.Is(err, ErrPingbackNoLink) {
+		t.Errorf("SendPingback returned error %v, want %v", err, ErrPingbackNoLink)
+	}
+	var fault *PingbackFault
+	if !errors.As(err, &fault) || fault.Code != 0x11 || fault.Message != "fault message" {
+		t.Errorf("SendPingback returned error %#v, want PingbackFault with code 0x11", err)
+	}
+
+	err = client.SendPingback(server.URL+"/other", "S", "T")
+	if !errors.As(err, &fault) || fault.Code != 99 {
+		t.Errorf("SendPingback returned error %#v, want PingbackFault with code 99", err)
+	}
+
+	// ensure 404 response is returned as error
+	if err := client.SendPingback(server.URL+"/bad", "S", "T"); err == nil {
+		t.Errorf("SendPingback did not return expected error")
+	}
+}
+
+func TestClient_Send(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	var calls [][2]string
+	mux.HandleFunc("/xmlrpc", pingbackHandler(t, &calls, 0))
+	mux.HandleFunc("/webmention", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/status")
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/wm", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</webmention>; rel=webmention")
+		w.Header().Set("X-Pingback", "/xmlrpc")
+	})
+	mux.HandleFunc("/pb", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Pingback", "/xmlrpc")
+	})
+	mux.HandleFunc("/none", func(w http.ResponseWriter, r *http.Request) {})
+	var flakyRequests int
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		// succeed for webmention discovery (HEAD and GET), then fail
+		if flakyRequests++; flakyRequests > 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	client := New(nil, WithPingbackFallback(true))
+
+	got, err := client.Send("S", server.URL+"/wm")
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	want := &SendResult{Endpoint: server.URL + "/webmention", StatusCode: http.StatusCreated, Location: "/status"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("Send returned %+v, want %+v", got, want)
+	}
+
+	got, err = client.Send("S", server.URL+"/pb")
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	want = &SendResult{Endpoint: server.URL + "/xmlrpc", Pingback: true}
+	if !cmp.Equal(got, want) {
+		t.Errorf("Send returned %+v, want %+v", got, want)
+	}
+	if want := [][2]string{{"S", server.URL + "/pb"}}; !cmp.Equal(calls, want) {
+		t.Errorf("pingback server received calls %v, want %v", calls, want)
+	}
+
+	// neither webmention nor pingback supported
+	if _, err := client.Send("S", server.URL+"/none"); err != ErrNoEndpointFound {
+		t.Errorf("Send returned error %v, want %v", err, ErrNoEndpointFound)
+	}
+
+	// pingback discovery failure is returned
+	_, err = client.Send("S", server.URL+"/flaky")
+	if err == nil || errors.Is(err, ErrNoEndpointFound) || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Send returned error %v, want pingback discovery error", err)
+	}
+
+	// without fallback
+	client = New(nil)
+	if _, err := client.Send("S", server.URL+"/pb"); err != ErrNoEndpointFound {
+		t.Errorf("Send returned error %v, want %v", err, ErrNoEndpointFound)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrTooManyRedirects is returned when a request exceeds the maximum number
+// of redirects set with WithMaxRedirects.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// Redirect is a single redirect followed while making a request.
+type Redirect struct {
+	From       string // URL that was redirected
+	To         string // URL redirected to
+	StatusCode int    // status code of the redirect response
+}
+
+// Permanent reports whether the redirect was permanent, using a 301 Moved
+// Permanently or 308 Permanent Redirect status.
+func (r Redirect) Permanent() bool {
+	return r.StatusCode == http.StatusMovedPermanently || r.StatusCode == http.StatusPermanentRedirect
+}
+
+// redirectChain returns the redirects that were followed to produce resp, in
+// the order they were followed.
+func redirectChain(resp *http.Response) []Redirect {
+	var chain []Redirect
+	for r := resp.Request; r != nil && r.Response != nil; r = r.Response.Request {
+		chain = append(chain, Redirect{
+			From:       r.Response.Request.URL.String(),
+			To:         r.URL.String(),
+			StatusCode: r.Response.StatusCode,
+		})
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// WithMaxRedirects limits the number of redirects followed by each request
+// made by the Client.  Requests exceeding the limit fail with an error
+// wrapping ErrTooManyRedirects.  A value of zero means no redirects are
+// followed.  Redirects within the limit are checked by the redirect policy
+// set by WithRedirectPolicy or, if none is set, the CheckRedirect function of
+// the configured http.Client.
+func WithMaxRedirects(n int) Option {
+	return func(c *Client) {
+		c.maxRedirects = &n
+	}
+}
+
+// limitRedirects wraps policy to fail once more than max redirects have been
+// followed.  If policy is nil, redirects within the limit are followed.
+func limitRedirects(max int, policy func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > max {
+			return ErrTooManyRedirects
+		}
+		if policy != nil {
+			return policy(req, via)
+		}
+		return nil
+	}
+}
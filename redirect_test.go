@@ -0,0 +1,92 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClient_DiscoverEndpointDetailed_Redirects(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	redirect := func(to string, code int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, to, code)
+		}
+	}
+	mux.HandleFunc("/old", redirect("/moved", http.StatusMovedPermanently))
+	mux.HandleFunc("/moved", redirect("/temp", http.StatusFound))
+	mux.HandleFunc("/temp", redirect("/post/", http.StatusPermanentRedirect))
+	mux.HandleFunc("/post/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "<endpoint>; rel=webmention")
+	})
+
+	client := New(nil)
+	d, err := client.DiscoverEndpointDetailed(server.URL + "/old")
+	if err != nil {
+		t.Fatalf("DiscoverEndpointDetailed returned error: %v", err)
+	}
+	want := &EndpointDiscovery{
+		Endpoint: server.URL + "/post/endpoint",
+		URL:      server.URL + "/post/",
+		Redirects: []Redirect{
+			{server.URL + "/old", server.URL + "/moved", http.StatusMovedPermanently},
+			{server.URL + "/moved", server.URL + "/temp", http.StatusFound},
+			{server.URL + "/temp", server.URL + "/post/", http.StatusPermanentRedirect},
+		},
+		Source: SourceHeader,
+	}
+	if !cmp.Equal(d, want) {
+		t.Errorf("DiscoverEndpointDetailed returned %+v, want %+v", d, want)
+	}
+	if got, want := d.CanonicalURL(), server.URL+"/moved"; got != want {
+		t.Errorf("CanonicalURL returned %v, want %v", got, want)
+	}
+
+	// no redirects
+	d, err = client.DiscoverEndpointDetailed(server.URL + "/post/")
+	if err != nil {
+		t.Fatalf("DiscoverEndpointDetailed returned error: %v", err)
+	}
+	if got, want := d.CanonicalURL(), server.URL+"/post/"; got != want {
+		t.Errorf("CanonicalURL returned %v, want %v", got, want)
+	}
+
+	// redirect limit
+	client = NewWithOptions(WithMaxRedirects(2))
+	if _, err := client.DiscoverEndpointDetailed(server.URL + "/old"); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("DiscoverEndpointDetailed returned error %v, want %v", err, ErrTooManyRedirects)
+	}
+	client = NewWithOptions(WithMaxRedirects(3))
+	if _, err := client.DiscoverEndpointDetailed(server.URL + "/old"); err != nil {
+		t.Errorf("DiscoverEndpointDetailed returned error: %v", err)
+	}
+
+	// redirect limit combined with redirect policy
+	errPolicy := errors.New("policy")
+	client = NewWithOptions(WithMaxRedirects(3), WithRedirectPolicy(func(*http.Request, []*http.Request) error {
+		return errPolicy
+	}))
+	if _, err := client.DiscoverEndpointDetailed(server.URL + "/old"); !errors.Is(err, errPolicy) {
+		t.Errorf("DiscoverEndpointDetailed returned error %v, want %v", err, errPolicy)
+	}
+
+	// redirect limit combined with the http.Client's CheckRedirect
+	hc := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return errPolicy
+	}}
+	client = New(hc, WithMaxRedirects(3))
+	if _, err := client.DiscoverEndpointDetailed(server.URL + "/old"); !errors.Is(err, errPolicy) {
+		t.Errorf("DiscoverEndpointDetailed returned error %v, want %v", err, errPolicy)
+	}
+	client = New(hc, WithMaxRedirects(0))
+	if _, err := client.DiscoverEndpointDetailed(server.URL + "/old"); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("DiscoverEndpointDetailed returned error %v, want %v", err, ErrTooManyRedirects)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A RetryPolicy decides whether a request should be retried.  It is called
+// after each attempt with the attempt number (starting at 1) and the response
+// and error returned by that attempt.  It returns whether to retry, and how
+// long to wait before doing so.
+type RetryPolicy func(attempt int, resp *http.Response, err error) (retry bool, wait time.Duration)
+
+// MaxRetryWait is the longest ExponentialBackoff will wait before retrying a
+// request, regardless of the base wait or any Retry-After header.
+const MaxRetryWait = time.Minute
+
+// ExponentialBackoff returns a RetryPolicy that retries a request up to
+// maxRetries times after network errors, 429 Too Many Requests responses, and
+// 5xx responses.  The wait before retrying starts at base and doubles with
+// each attempt, unless the response includes a Retry-After header specifying
+// a number of seconds.  Waits are limited to MaxRetryWait.
+func ExponentialBackoff(maxRetries int, base time.Duration) RetryPolicy {
+	return func(attempt int, resp *http.Response, err error) (bool, time.Duration) {
+		if attempt > maxRetries {
+			return false, 0
+		}
+		if err == nil && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return false, 0
+		}
+		if resp != nil {
+			if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+				if s > int(MaxRetryWait/time.Second) {
+					return true, MaxRetryWait
+				}
+				return true, time.Duration(s) * time.Second
+			}
+		}
+		wait := base << (attempt - 1)
+		if wait > MaxRetryWait || wait>>(attempt-1) != base {
+			// capped, or overflowed
+			wait = MaxRetryWait
+		}
+		return true, wait
+	}
+}
+
+// do sends req, retrying according to the configured RetryPolicy.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.Do(req)
+		if c.retry == nil {
+			return resp, err
+		}
+		retry, wait := c.retry(attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+
+		// requests with a body can only be retried if it can be recreated
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		c.log().Debug("retrying request", "method", req.Method, "url", req.URL.String(), "attempt", attempt, "wait", wait, "error", err)
+
+		t := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		case <-t.C:
+		}
+
+		req = req.Clone(req.Context())
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
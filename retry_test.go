@@ -0,0 +1,104 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithRetryPolicy(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	var attempts int
+	mux.HandleFunc("/endpoint", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if got, want := r.PostFormValue("source"), "S"; got != want {
+			t.Errorf("attempt %d contained source: %v, want %v", attempts, got, want)
+		}
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	client := NewWithOptions(WithRetryPolicy(ExponentialBackoff(2, time.Millisecond)))
+	resp, err := client.SendWebmention(server.URL+"/endpoint", "S", "T")
+	if err != nil {
+		t.Errorf("SendWebmention returned error: %v", err)
+	} else {
+		_ = resp.Body.Close()
+	}
+	if want := 3; attempts != want {
+		t.Errorf("server received %d attempts, want %d", attempts, want)
+	}
+
+	// retries are exhausted
+	attempts = 0
+	client = NewWithOptions(WithRetryPolicy(ExponentialBackoff(1, time.Millisecond)))
+	resp, err = client.SendWebmention(server.URL+"/endpoint", "S", "T")
+	if err == nil {
+		t.Errorf("SendWebmention did not return expected error")
+	}
+	_ = resp.Body.Close()
+	if want := 2; attempts != want {
+		t.Errorf("server received %d attempts, want %d", attempts, want)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	p := ExponentialBackoff(3, time.Second)
+	resp := func(code int, retryAfter string) *http.Response {
+		r := &http.Response{StatusCode: code, Header: make(http.Header)}
+		if retryAfter != "" {
+			r.Header.Set("Retry-After", retryAfter)
+		}
+		return r
+	}
+
+	tests := []struct {
+		attempt   int
+		resp      *http.Response
+		err       error
+		wantRetry bool
+		wantWait  time.Duration
+	}{
+		{1, resp(200, ""), nil, false, 0},
+		{1, resp(404, ""), nil, false, 0},
+		{1, resp(500, ""), nil, true, time.Second},
+		{2, resp(503, ""), nil, true, 2 * time.Second},
+		{3, resp(429, ""), nil, true, 4 * time.Second},
+		{4, resp(500, ""), nil, false, 0},
+		{1, resp(429, "10"), nil, true, 10 * time.Second},
+		{1, nil, errors.New("network error"), true, time.Second},
+		{1, resp(503, "86400"), nil, true, MaxRetryWait},
+	}
+	for _, tt := range tests {
+		retry, wait := p(tt.attempt, tt.resp, tt.err)
+		if retry != tt.wantRetry || wait != tt.wantWait {
+			t.Errorf("ExponentialBackoff(%d, %v, %v) returned %t, %v; want %t, %v", tt.attempt, tt.resp, tt.err, retry, wait, tt.wantRetry, tt.wantWait)
+		}
+	}
+}
+
+func TestExponentialBackoff_MaxWait(t *testing.T) {
+	p := ExponentialBackoff(100, time.Second)
+	tests := []struct {
+		attempt  int
+		wantWait time.Duration
+	}{
+		{6, 32 * time.Second},
+		{7, MaxRetryWait},
+		{40, MaxRetryWait},
+		{70, MaxRetryWait}, // base << 69 overflows
+	}
+	for _, tt := range tests {
+		_, wait := p(tt.attempt, nil, errors.New("network error"))
+		if wait != tt.wantWait {
+			t.Errorf("ExponentialBackoff attempt %d returned wait %v, want %v", tt.attempt, wait, tt.wantWait)
+		}
+	}
+}
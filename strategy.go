@@ -0,0 +1,107 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// DiscoveryStrategy determines which HTTP methods are used to discover
+// webmention endpoints.
+type DiscoveryStrategy int
+
+const (
+	// HeadThenGet makes a HEAD request to look for an HTTP Link header,
+	// falling back to a GET request if no endpoint is found.  Hosts where
+	// HEAD requests fail but GET requests succeed are remembered, and
+	// subsequent discoveries for those hosts use only GET.  This is the
+	// default.
+	HeadThenGet DiscoveryStrategy = iota
+
+	// GetOnly makes only a GET request, checking both HTTP Link headers and
+	// the HTML body.
+	GetOnly
+
+	// HeadOnly makes only a HEAD request, so only HTTP Link headers are
+	// checked.
+	HeadOnly
+)
+
+// WithDiscoveryStrategy sets the strategy used to discover webmention
+// endpoints.
+func WithDiscoveryStrategy(s DiscoveryStrategy) Option {
+	return func(c *Client) {
+		c.strategy = s
+	}
+}
+
+// headFailed reports whether a HEAD discovery request that returned status
+// and err indicates the server does not properly support HEAD requests.
+// Errors that may be temporary or unrelated to the request method, such as
+// timeouts, DNS and connection errors, and too many redirects, are not
+// considered failures.
+func headFailed(status int, err error) bool {
+	switch status {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	case 0:
+		return err != nil && protocolError(err)
+	}
+	return false
+}
+
+// protocolError reports whether err, returned by a request, indicates that
+// the server responded to the request improperly, such as by closing the
+// connection or sending a malformed response.
+func protocolError(err error) bool {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, ErrTooManyRedirects),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout(),
+		errors.As(err, &dnsErr),
+		errors.As(err, &opErr) && opErr.Op == "dial":
+		return false
+	}
+	return true
+}
+
+// hostSet is a set of hosts, safe for concurrent use.
+type hostSet struct {
+	mu    sync.Mutex
+	hosts map[string]bool
+}
+
+func (s *hostSet) add(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hosts == nil {
+		s.hosts = make(map[string]bool)
+	}
+	s.hosts[host] = true
+}
+
+func (s *hostSet) contains(host string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hosts[host]
+}
+
+// urlHost returns the host of urlStr, or an empty string if it cannot be
+// parsed.
+func urlHost(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,139 @@
+// Copyright (c) The webmention project authors.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package webmention
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWithDiscoveryStrategy(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	var methods []string
+	mux.HandleFunc("/header", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Header().Set("Link", "</endpoint>; rel=webmention")
+	})
+	mux.HandleFunc("/body", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		_, _ = w.Write([]byte(`<link href="/endpoint" rel="webmention">`))
+	})
+
+	tests := []struct {
+		strategy    DiscoveryStrategy
+		path        string
+		wantMethods []string
+		wantErr     bool
+	}{
+		{HeadThenGet, "/header", []string{"HEAD"}, false},
+		{HeadThenGet, "/body", []string{"HEAD", "GET"}, false},
+		{GetOnly, "/header", []string{"GET"}, false},
+		{GetOnly, "/body", []string{"GET"}, false},
+		{HeadOnly, "/header", []string{"HEAD"}, false},
+		{HeadOnly, "/body", []string{"HEAD"}, true},
+	}
+	for _, tt := range tests {
+		methods = nil
+		client := NewWithOptions(WithDiscoveryStrategy(tt.strategy))
+		_, err := client.DiscoverEndpoint(server.URL + tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("strategy %v: DiscoverEndpoint(%q) returned error %v, want error: %t", tt.strategy, tt.path, err, tt.wantErr)
+		}
+		if !cmp.Equal(methods, tt.wantMethods) {
+			t.Errorf("strategy %v: DiscoverEndpoint(%q) made requests %v, want %v", tt.strategy, tt.path, methods, tt.wantMethods)
+		}
+	}
+}
+
+func TestClient_DiscoverEndpoint_HeadUnsupported(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	var methods []string
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Link", "</endpoint>; rel=webmention")
+	})
+
+	client := New(nil)
+	for i := 0; i < 2; i++ {
+		if _, err := client.DiscoverEndpoint(server.URL + "/"); err != nil {
+			t.Errorf("DiscoverEndpoint returned error: %v", err)
+		}
+	}
+
+	// second discovery should skip HEAD
+	want := []string{"HEAD", "GET", "GET"}
+	if !cmp.Equal(methods, want) {
+		t.Errorf("DiscoverEndpoint made requests %v, want %v", methods, want)
+	}
+}
+
+func TestHeadFailed(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		want   bool
+	}{
+		{http.StatusMethodNotAllowed, nil, true},
+		{http.StatusNotImplemented, nil, true},
+		{http.StatusOK, nil, false},
+		{http.StatusNotFound, errors.New("response error: 404"), false},
+		{http.StatusServiceUnavailable, errors.New("response error: 503"), false},
+		{0, io.EOF, true},
+		{0, &url.Error{Op: "Head", URL: "u", Err: errors.New("malformed HTTP response")}, true},
+		{0, &url.Error{Op: "Head", URL: "u", Err: ErrTooManyRedirects}, false},
+		{0, &url.Error{Op: "Head", URL: "u", Err: context.DeadlineExceeded}, false},
+		{0, &url.Error{Op: "Head", URL: "u", Err: context.Canceled}, false},
+		{0, &url.Error{Op: "Head", URL: "u", Err: &net.DNSError{Err: "no such host", Name: "example.com"}}, false},
+		{0, &url.Error{Op: "Head", URL: "u", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}, false},
+	}
+	for _, tt := range tests {
+		if got := headFailed(tt.status, tt.err); got != tt.want {
+			t.Errorf("headFailed(%d, %v) returned %t, want %t", tt.status, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestClient_DiscoverEndpoint_HeadTransientError(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	var methods []string
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Method == http.MethodHead {
+			// redirect loop, failing with ErrTooManyRedirects
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.Header().Set("Link", "</endpoint>; rel=webmention")
+	})
+
+	client := NewWithOptions(WithMaxRedirects(0))
+	for i := 0; i < 2; i++ {
+		if _, err := client.DiscoverEndpoint(server.URL + "/"); err != nil {
+			t.Errorf("DiscoverEndpoint returned error: %v", err)
+		}
+	}
+
+	// HEAD is still used after a transient failure
+	want := []string{"HEAD", "GET", "HEAD", "GET"}
+	if !cmp.Equal(methods, want) {
+		t.Errorf("DiscoverEndpoint made requests %v, want %v", methods, want)
+	}
+}
@@ -7,10 +7,14 @@
 package webmention // import "willnorris.com/go/webmention"
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 const (
@@ -19,109 +23,428 @@ const (
 	relLegacySlash = "http://webmention.org/"
 )
 
+// webmentionRel reports whether rels contains a webmention rel value, and
+// whether it is only present as a legacy "http://webmention.org/" value.
+func webmentionRel(rels []string) (ok, legacy bool) {
+	for _, v := range rels {
+		switch v {
+		case relWebmention:
+			return true, false
+		case relLegacy, relLegacySlash:
+			ok, legacy = true, true
+		}
+	}
+	return ok, legacy
+}
+
 // Client is a webmention client that can discover webmention endpoints and send webmentions.
 type Client struct {
 	*http.Client
+
+	userAgent        string
+	header           http.Header // additional headers sent with every request
+	timeout          time.Duration
+	checkRedirect    func(req *http.Request, via []*http.Request) error
+	retry            RetryPolicy
+	cache            Cache
+	logger           *slog.Logger
+	maxBodySize      int64
+	maxRedirects     *int
+	instrumentation  Instrumentation
+	strategy         DiscoveryStrategy
+	noHead           *hostSet // hosts that do not support HEAD requests
+	pingbackFallback bool
+}
+
+// New constructs a new webmention Client using the provided http.Client and
+// options.  If a nil http.Client is provided, http.DefaultClient is used.
+func New(client *http.Client, opts ...Option) *Client {
+	c := &Client{Client: client, noHead: new(hostSet)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+
+	if c.maxRedirects != nil {
+		policy := c.checkRedirect
+		if policy == nil {
+			policy = c.Client.CheckRedirect
+		}
+		c.checkRedirect = limitRedirects(*c.maxRedirects, policy)
+	}
+
+	// apply client-level settings to a copy, so that the provided client
+	// (which may be http.DefaultClient) is not modified.
+	if c.timeout != 0 || c.checkRedirect != nil {
+		hc := *c.Client
+		if c.timeout != 0 {
+			hc.Timeout = c.timeout
+		}
+		if c.checkRedirect != nil {
+			hc.CheckRedirect = c.checkRedirect
+		}
+		c.Client = &hc
+	}
+	return c
+}
+
+// NewWithOptions constructs a new webmention Client configured by opts.  If no
+// http.Client is provided using WithHTTPClient, http.DefaultClient is used.
+func NewWithOptions(opts ...Option) *Client {
+	return New(nil, opts...)
+}
+
+// newRequest returns a new request with the configured User-Agent and
+// additional headers applied.
+func (c *Client) newRequest(ctx context.Context, method, urlStr string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, nil
 }
 
-// New constructs a new webmention Client using the provided http.Client.  If a
-// nil http.Client is provided, http.DefaultClient is used.
-func New(client *http.Client) *Client {
-	if client == nil {
-		client = http.DefaultClient
+// get issues a GET request to urlStr with the configured headers applied.
+func (c *Client) get(ctx context.Context, urlStr string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, urlStr, nil)
+	if err != nil {
+		return nil, err
 	}
-	return &Client{Client: client}
+	return c.do(req)
+}
+
+// limitBody returns body limited to the configured maximum body size.
+// Closing the returned body closes the original.
+func (c *Client) limitBody(body io.ReadCloser) io.ReadCloser {
+	if c.maxBodySize <= 0 {
+		return body
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(body, c.maxBodySize), body}
 }
 
 // SendWebmention sends a webmention to endpoint, indicating that source has mentioned target.
 func (c *Client) SendWebmention(endpoint, source, target string) (*http.Response, error) {
-	resp, err := c.PostForm(endpoint, url.Values{
+	return c.SendWebmentionContext(context.Background(), endpoint, source, target)
+}
+
+// SendWebmentionContext is like SendWebmention, but uses ctx for the request
+// and any waits between retries.  Instrumentation receives ctx, so the
+// operation can be traced as part of the caller's trace.
+func (c *Client) SendWebmentionContext(ctx context.Context, endpoint, source, target string) (resp *http.Response, err error) {
+	ctx, end := c.startSpan(ctx, OpSendWebmention, endpoint)
+	defer func() {
+		var status int
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		end(status, err)
+	}()
+
+	form := url.Values{
 		"source": []string{source},
 		"target": []string{target},
-	})
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
 	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	log := c.log().With("endpoint", endpoint, "source", source, "target", target)
+	resp, err = c.do(req)
+	if err != nil {
+		log.Error("webmention send failed", "error", err)
 		return resp, err
 	}
+	logRedirects(log, redirectChain(resp))
 	if code := resp.StatusCode; code < 200 || 300 <= code {
-		return resp, fmt.Errorf("response error: %v", resp.StatusCode)
+		err := fmt.Errorf("response error: %v", resp.StatusCode)
+		log.Error("webmention send failed", "status", resp.StatusCode, "error", err)
+		return resp, err
 	}
+	log.Info("webmention sent", "status", resp.StatusCode, "location", resp.Header.Get("Location"))
 	return resp, nil
 }
 
 // DiscoverEndpoint discovers the webmention endpoint for the provided URL.
 func (c *Client) DiscoverEndpoint(urlStr string) (string, error) {
-	headEndpoint, err := c.discoverRequest(http.MethodHead, urlStr)
-	if err == nil && headEndpoint != "" {
-		return headEndpoint, nil
+	return c.DiscoverEndpointContext(context.Background(), urlStr)
+}
+
+// DiscoverEndpointContext is like DiscoverEndpoint, but uses ctx for the
+// discovery requests and any waits between retries.
+func (c *Client) DiscoverEndpointContext(ctx context.Context, urlStr string) (string, error) {
+	if c.cache != nil {
+		if endpoint, ok := c.cache.Get(urlStr); ok {
+			return endpoint, nil
+		}
 	}
 
-	getEndpoint, err := c.discoverRequest(http.MethodGet, urlStr)
-	if err == nil && getEndpoint != "" {
-		return getEndpoint, nil
+	d, err := c.discoverEndpoint(ctx, urlStr, false)
+	if err != nil {
+		return "", err
 	}
+	return d.Endpoint, nil
+}
+
+// EndpointDiscovery is the detailed result of discovering the webmention
+// endpoint for a URL.
+type EndpointDiscovery struct {
+	// Endpoint is the absolute URL of the discovered webmention endpoint.
+	Endpoint string
 
-	return "", err
+	// URL is the final URL of the target after following redirects.
+	// Relative endpoint URLs are resolved against it.
+	URL string
+
+	// Redirects lists the redirects followed from the requested URL to URL,
+	// in order.
+	Redirects []Redirect
+
+	// Source is where Endpoint was advertised.
+	Source EndpointSource
+
+	// Legacy is true if Endpoint was advertised using the legacy
+	// "http://webmention.org/" rel value rather than "webmention".
+	Legacy bool
+
+	// Candidates lists the other webmention endpoints advertised by URL, in
+	// order of preference.  If the HeadOnly discovery strategy is used, only
+	// endpoints advertised in HTTP Link headers are included.
+	Candidates []EndpointCandidate
 }
 
-func (c *Client) discoverRequest(method, urlStr string) (string, error) {
-	req, err := http.NewRequest(method, urlStr, nil)
+// EndpointSource identifies where a webmention endpoint was advertised.
+type EndpointSource string
+
+// Sources of webmention endpoints.
+const (
+	SourceHeader EndpointSource = "header" // HTTP Link header
+	SourceLink   EndpointSource = "link"   // HTML <link> element
+	SourceA      EndpointSource = "a"      // HTML <a> element
+)
+
+// EndpointCandidate is a webmention endpoint advertised by a URL.
+type EndpointCandidate struct {
+	// Endpoint is the URL of the endpoint.
+	Endpoint string
+
+	// Source is where the endpoint was advertised.
+	Source EndpointSource
+
+	// Legacy is true if the endpoint was advertised using the legacy
+	// "http://webmention.org/" rel value.
+	Legacy bool
+}
+
+// CanonicalURL returns the URL of the target after following only the
+// leading permanent redirects from the requested URL.  Sources may send
+// webmentions to this URL rather than the original, since the target has
+// permanently moved there.
+func (d *EndpointDiscovery) CanonicalURL() string {
+	if len(d.Redirects) == 0 {
+		return d.URL
+	}
+	u := d.Redirects[0].From
+	for _, r := range d.Redirects {
+		if !r.Permanent() {
+			break
+		}
+		u = r.To
+	}
+	return u
+}
+
+// DiscoverEndpointDetailed discovers the webmention endpoint for the provided
+// URL, returning details about the discovery including the final URL of the
+// target, any redirects followed, and all other advertised endpoints.
+// Unless the HeadOnly discovery strategy is used, a GET request is always
+// made, so that endpoints advertised in the HTML body are included in
+// Candidates.  If no endpoint is found, the returned EndpointDiscovery
+// describes the last request made, and the error is ErrNoEndpointFound.
+func (c *Client) DiscoverEndpointDetailed(urlStr string) (*EndpointDiscovery, error) {
+	return c.DiscoverEndpointDetailedContext(context.Background(), urlStr)
+}
+
+// DiscoverEndpointDetailedContext is like DiscoverEndpointDetailed, but uses
+// ctx for the discovery requests and any waits between retries.
+func (c *Client) DiscoverEndpointDetailedContext(ctx context.Context, urlStr string) (*EndpointDiscovery, error) {
+	return c.discoverEndpoint(ctx, urlStr, true)
+}
+
+// discoverEndpoint discovers the webmention endpoint for urlStr using the
+// configured strategy.  If full is true, a HEAD request is only made when
+// using the HeadOnly strategy, so that all advertised endpoints are found.
+func (c *Client) discoverEndpoint(ctx context.Context, urlStr string, full bool) (d *EndpointDiscovery, err error) {
+	ctx, end := c.startSpan(ctx, OpDiscoverEndpoint, urlStr)
+	var status int
+	defer func() {
+		end(status, err)
+	}()
+
+	host := urlHost(urlStr)
+	var headErr bool
+	if c.strategy == HeadOnly || c.strategy == HeadThenGet && !full && !c.headUnsupported(host) {
+		d, status, err = c.discoverRequest(ctx, http.MethodHead, urlStr)
+		if err == nil && d.Endpoint != "" {
+			c.cacheEndpoint(urlStr, d.Endpoint)
+			return d, nil
+		}
+		if c.strategy == HeadOnly {
+			return d, err
+		}
+		headErr = headFailed(status, err)
+	}
+
+	d, status, err = c.discoverRequest(ctx, http.MethodGet, urlStr)
+	if headErr && 200 <= status && status < 300 && c.noHead != nil {
+		c.log().Debug("HEAD requests not supported, using GET for future discovery", "host", host)
+		c.noHead.add(host)
+	}
+	if err == nil && d.Endpoint != "" {
+		c.cacheEndpoint(urlStr, d.Endpoint)
+		return d, nil
+	}
+
+	return d, err
+}
+
+// headUnsupported reports whether host is known to not support HEAD requests.
+func (c *Client) headUnsupported(host string) bool {
+	return c.noHead != nil && c.noHead.contains(host)
+}
+
+// cacheEndpoint stores the discovered endpoint for urlStr in the configured
+// cache, if any.
+func (c *Client) cacheEndpoint(urlStr, endpoint string) {
+	if c.cache != nil {
+		c.cache.Set(urlStr, endpoint)
+	}
+}
+
+// discoverRequest discovers the webmention endpoint for urlStr using a single
+// request with the given method.  It returns the discovery result and the
+// status code of the response.  If a response was received, the result is
+// non-nil even if an error is returned.
+func (c *Client) discoverRequest(ctx context.Context, method, urlStr string) (*EndpointDiscovery, int, error) {
+	log := c.log().With("method", method, "url", urlStr)
+	req, err := c.newRequest(ctx, method, urlStr, nil)
 	if err != nil {
-		return "", err
+		return nil, 0, err
 	}
 
-	resp, err := c.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return "", err
+		log.Debug("webmention discovery request failed", "error", err)
+		return nil, 0, err
 	}
+	resp.Body = c.limitBody(resp.Body)
 	defer func() {
 		_ = resp.Body.Close()
 	}()
+	d := &EndpointDiscovery{
+		URL:       resp.Request.URL.String(),
+		Redirects: redirectChain(resp),
+	}
+	logRedirects(log, d.Redirects)
+	log.Debug("webmention discovery request", "status", resp.StatusCode)
 
 	if code := resp.StatusCode; code < 200 || 300 <= code {
-		return "", fmt.Errorf("response error: %v", resp.StatusCode)
+		return d, code, fmt.Errorf("response error: %v", resp.StatusCode)
 	}
 
-	endpoint, err := extractEndpoint(resp)
+	candidates, err := extractCandidates(resp)
 	if err != nil {
-		return "", err
+		log.Debug("no webmention endpoint found", "error", err)
+		return d, resp.StatusCode, err
 	}
 
-	urls, err := resolveReferences(resp.Request.URL.String(), endpoint)
-	if err != nil {
-		return "", err
+	for _, cand := range candidates {
+		urls, err := resolveReferences(d.URL, cand.Endpoint)
+		if err != nil {
+			return d, resp.StatusCode, err
+		}
+		if len(urls) == 0 {
+			continue // invalid endpoint URL
+		}
+		cand.Endpoint = urls[0]
+		if d.Endpoint == "" {
+			d.Endpoint, d.Source, d.Legacy = cand.Endpoint, cand.Source, cand.Legacy
+		} else {
+			d.Candidates = append(d.Candidates, cand)
+		}
+	}
+	if d.Endpoint == "" {
+		log.Debug("no webmention endpoint found", "error", ErrNoEndpointFound)
+		return d, resp.StatusCode, ErrNoEndpointFound
 	}
-	return urls[0], nil
+	log.Debug("webmention endpoint found", "endpoint", d.Endpoint, "from", d.Source, "legacy", d.Legacy)
+	return d, resp.StatusCode, nil
 }
 
-func extractEndpoint(resp *http.Response) (string, error) {
+// extractCandidates returns the webmention endpoints advertised by resp, in
+// order of preference: first HTTP Link headers, then HTML elements in
+// document order.  If none are found, ErrNoEndpointFound is returned.
+func extractCandidates(resp *http.Response) ([]EndpointCandidate, error) {
 	// first check http link headers
-	if endpoint, err := httpLink(resp.Header); err == nil {
-		return endpoint, nil
-	}
+	candidates := httpLinks(resp.Header)
 
 	// then look in the HTML body
-	endpoint, err := htmlLink(resp.Body)
-	if err != nil {
-		return "", err
+	links, err := htmlLinks(resp.Body)
+	if err != nil && len(candidates) == 0 {
+		return nil, err
 	}
-	return endpoint, nil
+	candidates = append(candidates, links...)
+
+	if len(candidates) == 0 {
+		return nil, ErrNoEndpointFound
+	}
+	return candidates, nil
 }
 
 // DiscoverLinks discovers URLs that the provided resource links to.  These are
 // candidates for sending webmentions to.  If non-empty, sel is a CSS selector
 // identifying the root node(s) to search in for links.
 func (c *Client) DiscoverLinks(urlStr string, sel string) ([]string, error) {
-	resp, err := c.Get(urlStr)
+	return c.DiscoverLinksContext(context.Background(), urlStr, sel)
+}
+
+// DiscoverLinksContext is like DiscoverLinks, but uses ctx for the request and
+// any waits between retries.
+func (c *Client) DiscoverLinksContext(ctx context.Context, urlStr string, sel string) (links []string, err error) {
+	ctx, end := c.startSpan(ctx, OpDiscoverLinks, urlStr)
+	var status int
+	defer func() {
+		end(status, err)
+	}()
+
+	log := c.log().With("url", urlStr)
+	resp, err := c.get(ctx, urlStr)
 	if err != nil {
+		log.Debug("link discovery request failed", "error", err)
 		return nil, err
 	}
+	status = resp.StatusCode
+	logRedirects(log, redirectChain(resp))
+	log.Debug("link discovery request", "status", resp.StatusCode)
 	if code := resp.StatusCode; code < 200 || 300 <= code {
 		return nil, fmt.Errorf("response error: %v", resp.StatusCode)
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
-	return DiscoverLinksFromReader(resp.Body, urlStr, sel)
+	return DiscoverLinksFromReader(c.limitBody(resp.Body), urlStr, sel)
 }
 
 // DiscoverLinksFromReader discovers URLs in the HTML read from 'r'. Relative
@@ -116,27 +116,27 @@ func TestClient_DiscoverEndpoint(t *testing.T) {
 	}
 }
 
-func TestExtractEndpoint(t *testing.T) {
+func TestExtractCandidates(t *testing.T) {
 	tests := []struct {
-		resp string // raw response header and body
-		want string // wanted endpoint URL
+		resp string   // raw response header and body
+		want []string // wanted endpoint URLs
 	}{
 		{
 			`Link: </endpoint>; rel="webmention"
 
 `,
-			"/endpoint",
+			[]string{"/endpoint"},
 		},
 		{
 			`
 <link href="/endpoint" rel="webmention">`,
-			"/endpoint",
+			[]string{"/endpoint"},
 		},
 		{
 			`Link: </endpoint1>; rel="webmention"
 
 <link href="/endpoint2" rel="webmention">`,
-			"/endpoint1",
+			[]string{"/endpoint1", "/endpoint2"},
 		},
 	}
 
@@ -147,12 +147,25 @@ func TestExtractEndpoint(t *testing.T) {
 			t.Errorf("error reading response %q: %v", raw, err)
 		}
 
-		if got, err := extractEndpoint(resp); err != nil {
-			t.Errorf("extractEndpoint(%q) returned error: %v", raw, err)
-		} else if got != tt.want {
-			t.Errorf("extractEndpoint(%q) returned %v, want %v", raw, got, tt.want)
+		candidates, err := extractCandidates(resp)
+		if err != nil {
+			t.Errorf("extractCandidates(%q) returned error: %v", raw, err)
+			continue
+		}
+		var got []string
+		for _, c := range candidates {
+			got = append(got, c.Endpoint)
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("extractCandidates(%q) returned %v, want %v", raw, got, tt.want)
 		}
 	}
+
+	raw := "HTTP/1.1 200 OK\n\n<a href=\"/endpoint\">"
+	resp, _ := http.ReadResponse(bufio.NewReader(bytes.NewBufferString(raw)), nil)
+	if _, err := extractCandidates(resp); err != ErrNoEndpointFound {
+		t.Errorf("extractCandidates(%q) returned error %v, want %v", raw, err, ErrNoEndpointFound)
+	}
 }
 
 func TestDiscoverLinks(t *testing.T) {
@@ -187,3 +200,96 @@ func TestDiscoverLinks(t *testing.T) {
 		t.Errorf("DiscoverLinks returned %v, want %v", got, want)
 	}
 }
+
+func TestClient_Headers(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	client := New(nil,
+		WithUserAgent("test-agent"),
+		WithHeader("X-Test", "a"),
+		WithHeader("X-Test", "b"),
+		WithAccept("text/html"),
+	)
+
+	var requests int
+	check := func(r *http.Request) {
+		requests++
+		if got, want := r.Header.Get("User-Agent"), "test-agent"; got != want {
+			t.Errorf("%s %s sent User-Agent %q, want %q", r.Method, r.URL, got, want)
+		}
+		if got, want := r.Header.Values("X-Test"), []string{"a", "b"}; !cmp.Equal(got, want) {
+			t.Errorf("%s %s sent X-Test %q, want %q", r.Method, r.URL, got, want)
+		}
+		if got, want := r.Header.Get("Accept"), "text/html"; got != want {
+			t.Errorf("%s %s sent Accept %q, want %q", r.Method, r.URL, got, want)
+		}
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		check(r)
+		_, _ = fmt.Fprint(w, `<a href="/endpoint" rel="webmention">`)
+	})
+	mux.HandleFunc("/endpoint", func(w http.ResponseWriter, r *http.Request) {
+		check(r)
+		if got, want := r.PostFormValue("source"), "S"; got != want {
+			t.Errorf("request contained source: %v, want %v", got, want)
+		}
+	})
+
+	if _, err := client.DiscoverEndpoint(server.URL); err != nil {
+		t.Errorf("DiscoverEndpoint returned error: %v", err)
+	}
+	if _, err := client.DiscoverLinks(server.URL, ""); err != nil {
+		t.Errorf("DiscoverLinks returned error: %v", err)
+	}
+	resp, err := client.SendWebmention(server.URL+"/endpoint", "S", "T")
+	if err != nil {
+		t.Errorf("SendWebmention returned error: %v", err)
+	} else {
+		_ = resp.Body.Close()
+	}
+
+	// HEAD and GET for discovery, GET for links, POST for send
+	if want := 4; requests != want {
+		t.Errorf("server received %d requests, want %d", requests, want)
+	}
+}
+
+func TestClient_DiscoverEndpointDetailed(t *testing.T) {
+	mux, server, cleanup := testServer()
+	defer cleanup()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `</header>; rel="http://webmention.org/"`)
+		_, _ = fmt.Fprint(w, `<html><head>
+<link href="/link" rel="webmention">
+<link href="http://[::1" rel="webmention">
+</head><body>
+<a href="/a" rel="webmention">
+</body></html>`)
+	})
+
+	want := &EndpointDiscovery{
+		Endpoint: server.URL + "/header",
+		URL:      server.URL + "/",
+		Source:   SourceHeader,
+		Legacy:   true,
+		Candidates: []EndpointCandidate{
+			{server.URL + "/link", SourceLink, false},
+			{server.URL + "/a", SourceA, false},
+		},
+	}
+
+	// body candidates are included even though the HEAD request would
+	// find the header endpoint
+	for _, s := range []DiscoveryStrategy{HeadThenGet, GetOnly} {
+		client := NewWithOptions(WithDiscoveryStrategy(s))
+		got, err := client.DiscoverEndpointDetailed(server.URL + "/")
+		if err != nil {
+			t.Fatalf("strategy %v: DiscoverEndpointDetailed returned error: %v", s, err)
+		}
+		if !cmp.Equal(got, want) {
+			t.Errorf("strategy %v: DiscoverEndpointDetailed returned %+v, want %+v", s, got, want)
+		}
+	}
+}